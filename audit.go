@@ -0,0 +1,239 @@
+package echozap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// auditKey is the context key SetAudit stores the audit record under
+const auditKey = "_echozap_audit_"
+
+// AuditRecord is a compliance audit record of a request
+type AuditRecord struct {
+	Actor    string
+	Action   string
+	Resource string
+	Outcome  string
+	// Timestamp defaults to the time the request completed
+	Timestamp time.Time
+}
+
+// complete reports whether every descriptive field of the record is set
+func (r AuditRecord) complete() bool {
+	return r.Actor != "" && r.Action != "" && r.Resource != "" && r.Outcome != ""
+}
+
+// SetAudit records the audit record of the current request. The middleware logs it as a dedicated
+// "Audit" entry, in addition to the access log, to Options.AuditLogger; the last call wins. Requests
+// audited by ZapAuditLogger are logged by it instead, the record completing the derived one.
+func SetAudit(c echo.Context, record AuditRecord) {
+	c.Set(auditKey, record)
+}
+
+// auditRecord returns the record stored by SetAudit, if any
+func auditRecord(c echo.Context) (AuditRecord, bool) {
+	record, ok := c.Get(auditKey).(AuditRecord)
+	return record, ok
+}
+
+// auditFields returns the fields of an audit entry. Missing fields are logged empty and
+// "audit_complete" tells whether any is missing.
+func auditFields(record AuditRecord, end time.Time) []zapcore.Field {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = end
+	}
+
+	return []zapcore.Field{
+		zap.String("actor", record.Actor),
+		zap.String("action", record.Action),
+		zap.String("resource", record.Resource),
+		zap.String("outcome", record.Outcome),
+		zap.String("timestamp", record.Timestamp.Format(time.RFC3339Nano)),
+		zap.Bool("audit_complete", record.complete()),
+	}
+}
+
+// AuditOptions configures ZapAuditLogger.
+type AuditOptions struct {
+	// Logger is the dedicated zap logger audit entries are written to
+	Logger *zap.Logger
+	// Methods are the audited request methods (default: POST, PUT, PATCH and DELETE)
+	Methods []string
+	// UserResolver identifies the actor, such as JWTSubject: the value of the first string field it
+	// returns is the "actor"
+	UserResolver func(c echo.Context) []zapcore.Field
+	// BodyDigest adds a "body_sha256" field with the hex SHA-256 digest of the request body read by the
+	// handler
+	BodyDigest bool
+	// BodyFields are the top-level fields of JSON request bodies to log under "body_fields"
+	BodyFields []string
+	// MaxBodySize is the number of body bytes kept to extract BodyFields (default: echozap.DefaultMaxBodySize)
+	MaxBodySize int
+	// Clock returns the current time, used to timestamp entries (default: time.Now)
+	Clock func() time.Time
+}
+
+// ZapAuditLogger is a middleware writing an "Audit" entry for each state-changing request to a dedicated
+// logger, so audit trails can be kept apart from access logs. Entries share the fields of the records set
+// with SetAudit, derived from the request: the "actor" from UserResolver, the "action" (method), the
+// "resource" (route), the "outcome" (success below 400, failure otherwise) and the "timestamp" the
+// request completed. The fields of a record set by the handler take precedence, and the record is not
+// logged again by ZapLogger. Entries also carry the "resource_params", "status" and "request_id", plus
+// the body fields enabled by the options. Handler errors are passed to the echo error handler, to audit
+// the final status, and returned for the middleware around it such as ZapLogger.
+func ZapAuditLogger(options *AuditOptions) echo.MiddlewareFunc {
+	if len(options.Methods) == 0 {
+		options.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !containsString(options.Methods, req.Method) {
+				return next(c)
+			}
+
+			var body *auditBody
+			if (options.BodyDigest || len(options.BodyFields) > 0) && req.Body != nil {
+				body = &auditBody{ReadCloser: req.Body}
+				if options.BodyDigest {
+					body.digest = sha256.New()
+				}
+				if len(options.BodyFields) > 0 {
+					body.capture = newLimitedBuffer(options.MaxBodySize)
+				}
+				req.Body = body
+			}
+
+			// the error is handled to audit the final status, and still returned for the access log; the echo
+			// error handler doesn't respond twice
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			record := derivedAuditRecord(options, c, status)
+			if set, ok := auditRecord(c); ok {
+				record = mergeAuditRecord(record, set)
+				c.Set(auditKey, nil)
+			}
+
+			fields := append(auditFields(record, options.Clock()),
+				zap.Object("resource_params", newParamsObject(c)),
+				zap.Int("status", status),
+				zap.String("request_id", requestID(c)),
+			)
+			if body != nil && body.digest != nil {
+				fields = append(fields, zap.String("body_sha256", hex.EncodeToString(body.digest.Sum(nil))))
+			}
+			if body != nil && body.capture != nil {
+				if values, ok := jsonBodyFields(body.capture.Bytes(), options.BodyFields); ok {
+					fields = append(fields, zap.Object("body_fields", values))
+				}
+			}
+
+			options.Logger.Info("Audit", fields...)
+			return err
+		}
+	}
+}
+
+// derivedAuditRecord returns the audit record of a request derived from the request and its status
+func derivedAuditRecord(options *AuditOptions, c echo.Context, status int) AuditRecord {
+	record := AuditRecord{Action: c.Request().Method, Resource: matchedRoute(c), Outcome: "success"}
+	if record.Resource == "" {
+		record.Resource = c.Request().URL.Path
+	}
+	if status >= 400 || status == 0 {
+		record.Outcome = "failure"
+	}
+
+	if options.UserResolver != nil {
+		for _, f := range options.UserResolver(c) {
+			if f.Type == zapcore.StringType {
+				record.Actor = f.String
+				break
+			}
+		}
+	}
+	return record
+}
+
+// mergeAuditRecord returns record with the fields set in override replacing its own
+func mergeAuditRecord(record, override AuditRecord) AuditRecord {
+	if override.Actor != "" {
+		record.Actor = override.Actor
+	}
+	if override.Action != "" {
+		record.Action = override.Action
+	}
+	if override.Resource != "" {
+		record.Resource = override.Resource
+	}
+	if override.Outcome != "" {
+		record.Outcome = override.Outcome
+	}
+	if !override.Timestamp.IsZero() {
+		record.Timestamp = override.Timestamp
+	}
+	return record
+}
+
+// auditBody wraps a request body to digest and capture what the handler reads
+type auditBody struct {
+	io.ReadCloser
+	digest  hash.Hash
+	capture *limitedBuffer
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if b.digest != nil {
+			b.digest.Write(p[:n])
+		}
+		if b.capture != nil {
+			b.capture.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+// jsonFieldsObject marshals the selected top-level fields of a JSON object
+type jsonFieldsObject struct {
+	values map[string]interface{}
+	names  []string
+}
+
+func (o jsonFieldsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, name := range o.names {
+		if value, ok := o.values[name]; ok {
+			if err := enc.AddReflected(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonBodyFields decodes a JSON object body, false when it isn't one or was truncated
+func jsonBodyFields(body []byte, names []string) (jsonFieldsObject, bool) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(body, &values); err != nil || values == nil {
+		return jsonFieldsObject{}, false
+	}
+	return jsonFieldsObject{values: values, names: names}, true
+}
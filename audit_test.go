@@ -0,0 +1,250 @@
+package echozap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerAudit(t *testing.T) {
+	at := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	obs, logs := observer.New(zap.DebugLevel)
+	auditObs, audits := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), AuditLogger: zap.New(auditObs)}))
+	e.DELETE("/users/:id", func(c echo.Context) error {
+		SetAudit(c, AuditRecord{Actor: "alice", Action: "delete", Resource: "user/" + c.Param("id"), Outcome: "success", Timestamp: at})
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/42", nil))
+
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, 1, audits.Len())
+	assert.Equal(t, "Audit", audits.All()[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		"actor":          "alice",
+		"action":         "delete",
+		"resource":       "user/42",
+		"outcome":        "success",
+		"timestamp":      "2019-11-21T10:00:00Z",
+		"audit_complete": true,
+	}, audits.All()[0].ContextMap())
+}
+
+func TestZapLoggerAuditIncomplete(t *testing.T) {
+	end := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		SetAudit(c, AuditRecord{Action: "read", Resource: "reports"})
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), Clock: stepClock(end, 0)})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, logs.Len())
+
+	audit := logs.FilterMessage("Audit").All()[0].ContextMap()
+	assert.Equal(t, "", audit["actor"])
+	assert.Equal(t, "", audit["outcome"])
+	assert.Equal(t, "2019-11-21T10:00:00Z", audit["timestamp"])
+	assert.Equal(t, false, audit["audit_complete"])
+}
+
+func TestZapLoggerWithoutAudit(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, logs.FilterMessage("Audit").Len())
+}
+
+func TestZapAuditLogger(t *testing.T) {
+	at := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapAuditLogger(&AuditOptions{
+		Logger:       zap.New(obs),
+		UserResolver: ContextUser("user"),
+		BodyDigest:   true,
+		BodyFields:   []string{"name", "role"},
+		Clock:        stepClock(at, 0),
+	}))
+	e.PUT("/users/:id", func(c echo.Context) error {
+		c.Set("user", "alice")
+		if _, err := ioutil.ReadAll(c.Request().Body); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	body := `{"name":"Alice","role":"admin","password":"secret"}`
+	req := httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	digest := sha256.Sum256([]byte(body))
+
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "Audit", logs.All()[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		"actor":           "alice",
+		"action":          http.MethodPut,
+		"resource":        "/users/:id",
+		"resource_params": map[string]interface{}{"id": "42"},
+		"status":          int64(http.StatusNoContent),
+		"outcome":         "success",
+		"timestamp":       "2019-11-21T10:00:00Z",
+		"request_id":      "req-1",
+		"body_sha256":     hex.EncodeToString(digest[:]),
+		"body_fields":     map[string]interface{}{"name": "Alice", "role": "admin"},
+		"audit_complete":  true,
+	}, logs.All()[0].ContextMap())
+}
+
+func TestZapAuditLoggerRecord(t *testing.T) {
+	at := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	obs, logs := observer.New(zap.DebugLevel)
+	auditObs, audits := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), AuditLogger: zap.New(auditObs)}))
+	e.Use(ZapAuditLogger(&AuditOptions{Logger: zap.New(auditObs), Clock: stepClock(at, 0)}))
+	e.DELETE("/users/:id", func(c echo.Context) error {
+		SetAudit(c, AuditRecord{Actor: "alice", Action: "delete", Outcome: "denied"})
+		return c.NoContent(http.StatusForbidden)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/42", nil))
+
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, 1, audits.Len())
+
+	fields := audits.All()[0].ContextMap()
+	assert.Equal(t, "alice", fields["actor"])
+	assert.Equal(t, "delete", fields["action"])
+	assert.Equal(t, "/users/:id", fields["resource"])
+	assert.Equal(t, "denied", fields["outcome"])
+	assert.Equal(t, "2019-11-21T10:00:00Z", fields["timestamp"])
+	assert.Equal(t, true, fields["audit_complete"])
+	assert.Equal(t, int64(http.StatusForbidden), fields["status"])
+}
+
+func TestZapAuditLoggerFailure(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("not json"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		ioutil.ReadAll(c.Request().Body)
+		return echo.NewHTTPError(http.StatusForbidden)
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapAuditLogger(&AuditOptions{Logger: zap.New(obs), BodyFields: []string{"name"}})(h)(c)
+
+	if assert.IsType(t, &echo.HTTPError{}, err) {
+		assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	}
+	assert.Equal(t, 1, logs.Len())
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "failure", fields["outcome"])
+	assert.Equal(t, int64(http.StatusForbidden), fields["status"])
+	assert.Equal(t, "/users", fields["resource"])
+	assert.NotContains(t, fields, "body_fields")
+	assert.NotContains(t, fields, "body_sha256")
+}
+
+func TestZapAuditLoggerHandlerError(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	auditObs, audits := observer.New(zap.DebugLevel)
+
+	var resolved error
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:          zap.New(obs),
+		LogErrorHandled: true,
+		LevelResolver: func(c echo.Context, err error) zapcore.Level {
+			resolved = err
+			return zapcore.ErrorLevel
+		},
+	}))
+	e.Use(ZapAuditLogger(&AuditOptions{Logger: zap.New(auditObs)}))
+	e.POST("/users", func(c echo.Context) error {
+		return errors.New("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, 1, audits.Len())
+	assert.Equal(t, "failure", audits.All()[0].ContextMap()["outcome"])
+
+	assert.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, int64(http.StatusInternalServerError), fields["status"])
+	assert.Equal(t, "boom", fields["error"])
+	assert.Equal(t, true, fields["error_handled_by_middleware"])
+	assert.EqualError(t, resolved, "boom")
+}
+
+func TestZapAuditLoggerRecycledContext(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapAuditLogger(&AuditOptions{Logger: zap.New(obs)}))
+	e.DELETE("/users/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/2", nil))
+
+	for i, id := range []string{"1", "2"} {
+		assert.Equal(t, map[string]interface{}{"id": id}, logs.All()[i].ContextMap()["resource_params"])
+	}
+}
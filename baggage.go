@@ -0,0 +1,65 @@
+package echozap
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// headerBaggage is the W3C baggage header
+const headerBaggage = "Baggage"
+
+// parseBaggage returns the values of the wanted keys found in W3C baggage headers. Members are
+// separated by commas, their properties (after ";") are ignored and values are percent-decoded.
+// Malformed members are skipped.
+func parseBaggage(header http.Header, keys []string) map[string]string {
+	values := map[string]string{}
+
+	for _, line := range header[http.CanonicalHeaderKey(headerBaggage)] {
+		for _, member := range strings.Split(line, ",") {
+			if i := strings.IndexByte(member, ';'); i >= 0 {
+				member = member[:i]
+			}
+
+			i := strings.IndexByte(member, '=')
+			if i < 0 {
+				continue
+			}
+
+			key := strings.TrimSpace(member[:i])
+			if key == "" || !containsString(keys, key) {
+				continue
+			}
+
+			value, err := url.PathUnescape(strings.TrimSpace(member[i+1:]))
+			if err != nil {
+				continue
+			}
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+// baggageObject marshals baggage values as a zap object
+type baggageObject map[string]string
+
+func (b baggageObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for key, value := range b {
+		enc.AddString(key, value)
+	}
+	return nil
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
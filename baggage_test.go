@@ -0,0 +1,43 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseBaggage(t *testing.T) {
+	header := http.Header{}
+	header.Add("baggage", "tenant=acme, plan = gold;ttl=60 ,broken, =nokey")
+	header.Add("baggage", "region=eu%20west,bad=%zz,ignored=1")
+
+	assert.Equal(t, map[string]string{
+		"tenant": "acme",
+		"plan":   "gold",
+		"region": "eu west",
+	}, parseBaggage(header, []string{"tenant", "plan", "region", "bad", "broken"}))
+}
+
+func TestZapLoggerBaggage(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.Header.Set("baggage", "tenant=acme,user=42,plan=gold")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogBaggageKeys: []string{"tenant", "plan"}})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"tenant": "acme", "plan": "gold"}, logs.All()[0].ContextMap()["baggage"])
+}
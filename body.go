@@ -0,0 +1,98 @@
+package echozap
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultMaxBodySize is the number of body bytes captured when no limit is configured.
+const DefaultMaxBodySize = 4 << 10
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// newLimitedBuffer returns a buffer keeping up to limit bytes, DefaultMaxBodySize when limit is not positive
+func newLimitedBuffer(limit int) *limitedBuffer {
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	return &limitedBuffer{limit: limit}
+}
+
+// Write never fails, bytes past the limit are dropped
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the kept bytes
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// debugBodyRequested tells whether the handler set the sentinel header to a true value
+func debugBodyRequested(header http.Header, name string) bool {
+	return isTruthy(header.Get(name))
+}
+
+// isTruthy reports whether a header or query value reads as true
+func isTruthy(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// bodyLimit returns the capture limit of a body, MaxBodySize when it is logged by LogRequestBody or
+// LogResponseBody and DebugBodyLimit otherwise
+func bodyLimit(options *Options, logged bool) int {
+	if logged {
+		return options.MaxBodySize
+	}
+	return options.DebugBodyLimit
+}
+
+// bodyAllowed reports whether a body of contentType may be logged given the allowed media type prefixes
+func bodyAllowed(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	mt := mediaType(contentType)
+	for _, prefix := range allowed {
+		if strings.HasPrefix(mt, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyFields returns the captured body under key, redacted by options.BodyRedactor, and whether it was
+// truncated
+func bodyFields(options *Options, c echo.Context, key string, capture *limitedBuffer) []zapcore.Field {
+	body := capture.Bytes()
+	if options.BodyRedactor != nil {
+		body = options.BodyRedactor(body, c)
+	}
+
+	fields := []zapcore.Field{zap.ByteString(key, body)}
+	if capture.truncated {
+		fields = append(fields, zap.Bool(key+"_truncated", true))
+	}
+	return fields
+}
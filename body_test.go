@@ -0,0 +1,136 @@
+package echozap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLimitedBuffer(t *testing.T) {
+	b := newLimitedBuffer(5)
+
+	n, err := b.Write([]byte("abc"))
+	assert.Equal(t, 3, n)
+	assert.Nil(t, err)
+	assert.False(t, b.truncated)
+
+	n, err = b.Write([]byte("defgh"))
+	assert.Equal(t, 5, n)
+	assert.Nil(t, err)
+	assert.True(t, b.truncated)
+	assert.Equal(t, "abcde", string(b.Bytes()))
+}
+
+func TestZapLoggerDebugBody(t *testing.T) {
+	for _, flagged := range []bool{true, false} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/something", strings.NewReader(`{"name":"bob"}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			if _, err := ioutil.ReadAll(c.Request().Body); err != nil {
+				return err
+			}
+			if flagged {
+				c.Response().Header().Set("X-Debug-Log-Body", "true")
+			}
+			return c.String(http.StatusOK, "a fairly long response body")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), DebugBodyHeader: "X-Debug-Log-Body", DebugBodyLimit: 10})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "a fairly long response body", rec.Body.String())
+		assert.Empty(t, rec.Header().Get("X-Debug-Log-Body"))
+
+		logFields := logs.All()[0].ContextMap()
+		if flagged {
+			assert.Equal(t, `{"name":"b`, logFields["request_body"])
+			assert.Equal(t, "a fairly l", logFields["response_body"])
+		} else {
+			assert.NotContains(t, logFields, "request_body")
+			assert.NotContains(t, logFields, "response_body")
+		}
+	}
+}
+
+func TestZapLoggerBodies(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"bob","password":"hunter2"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		if _, err := ioutil.ReadAll(c.Request().Body); err != nil {
+			return err
+		}
+		return c.JSONBlob(http.StatusOK, []byte(`{"token":"0123456789abcdef"}`))
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:          zap.New(obs),
+		LogRequestBody:  true,
+		LogResponseBody: true,
+		MaxBodySize:     20,
+		BodyRedactor: func(body []byte, c echo.Context) []byte {
+			return bytes.Replace(body, []byte("hunter2"), []byte("***"), -1)
+		},
+	})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"token":"0123456789abcdef"}`, rec.Body.String())
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, `{"user":"bob","passw`, logFields["request_body"])
+	assert.Equal(t, true, logFields["request_body_truncated"])
+	assert.Equal(t, `{"token":"0123456789`, logFields["response_body"])
+	assert.Equal(t, true, logFields["response_body_truncated"])
+}
+
+func TestZapLoggerBodiesRedactedAndFiltered(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"password":"hunter2"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		if _, err := ioutil.ReadAll(c.Request().Body); err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, "image/png", []byte{0x89, 0x50, 0x4e, 0x47})
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:           zap.New(obs),
+		LogRequestBody:   true,
+		LogResponseBody:  true,
+		BodyContentTypes: []string{"application/json", "text/"},
+		BodyRedactor: func(body []byte, c echo.Context) []byte {
+			return bytes.Replace(body, []byte("hunter2"), []byte("***"), -1)
+		},
+	})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, `{"password":"***"}`, logFields["request_body"])
+	assert.NotContains(t, logFields, "request_body_truncated")
+	assert.NotContains(t, logFields, "response_body")
+}
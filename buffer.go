@@ -0,0 +1,118 @@
+package echozap
+
+import (
+	"bufio"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultBufferSize is the buffer size used by NewBufferedWriteSyncer when none is given.
+	DefaultBufferSize = 256 * 1024
+	// DefaultFlushInterval is the flush interval used by NewBufferedWriteSyncer when none is given.
+	DefaultFlushInterval = 30 * time.Second
+)
+
+// BufferedWriteSyncer buffers writes to a zapcore.WriteSyncer and flushes them in batches, when the
+// buffer is full and every flush interval. It trades a little latency before entries are visible for
+// far fewer write syscalls under load. Call Stop on shutdown to flush the remaining entries.
+type BufferedWriteSyncer struct {
+	mu     sync.Mutex
+	ws     zapcore.WriteSyncer
+	writer *bufio.Writer
+
+	tick       <-chan time.Time
+	stopTicker func()
+	stop       chan struct{}
+	stopped    bool
+	done       chan struct{}
+}
+
+// NewBufferedWriteSyncer returns a BufferedWriteSyncer writing to ws with a buffer of size bytes flushed
+// at least every interval. Zero values use DefaultBufferSize and DefaultFlushInterval.
+//
+//	ws := echozap.NewBufferedWriteSyncer(zapcore.AddSync(file), 0, time.Second)
+//	defer ws.Stop()
+//	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(config), ws, zap.InfoLevel))
+func NewBufferedWriteSyncer(ws zapcore.WriteSyncer, size int, interval time.Duration) *BufferedWriteSyncer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	return newBufferedWriteSyncer(ws, size, ticker.C, ticker.Stop)
+}
+
+// newBufferedWriteSyncer returns a BufferedWriteSyncer flushed on each tick, stopTicker being called once
+// it stops
+func newBufferedWriteSyncer(ws zapcore.WriteSyncer, size int, tick <-chan time.Time, stopTicker func()) *BufferedWriteSyncer {
+	s := &BufferedWriteSyncer{
+		ws:         ws,
+		writer:     bufio.NewWriterSize(ws, size),
+		tick:       tick,
+		stopTicker: stopTicker,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go s.flushLoop()
+
+	return s
+}
+
+// Write buffers p, flushing the buffer first when p doesn't fit.
+func (s *BufferedWriteSyncer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(p) > s.writer.Available() && s.writer.Buffered() > 0 {
+		if err := s.writer.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return s.writer.Write(p)
+}
+
+// Sync flushes the buffer and syncs the underlying WriteSyncer.
+func (s *BufferedWriteSyncer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	return s.ws.Sync()
+}
+
+// Stop stops the periodic flush and flushes the buffer. Writes after Stop are flushed only when the
+// buffer fills up or Sync is called.
+func (s *BufferedWriteSyncer) Stop() error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return s.Sync()
+}
+
+// flushLoop flushes the buffer every tick until Stop is called
+func (s *BufferedWriteSyncer) flushLoop() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.tick:
+			s.Sync()
+		case <-s.stop:
+			s.stopTicker()
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package echozap
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request kinds logged by Options.LogRequestKind.
+const (
+	RequestKindJSON      = "json"
+	RequestKindForm      = "form"
+	RequestKindMultipart = "multipart"
+	RequestKindOther     = "other"
+)
+
+// Response formats logged by Options.LogResponseFormat.
+const (
+	ResponseFormatJSON   = "json"
+	ResponseFormatXML    = "xml"
+	ResponseFormatHTML   = "html"
+	ResponseFormatText   = "text"
+	ResponseFormatBinary = "binary"
+)
+
+// Latency buckets logged with Options.LatencyBuckets.
+const (
+	LatencyFast       = "fast"
+	LatencyAcceptable = "acceptable"
+	LatencySlow       = "slow"
+	LatencyCritical   = "critical"
+)
+
+// LatencyBuckets are the inclusive upper bounds of the latency buckets. Latencies above Slow are critical.
+type LatencyBuckets struct {
+	Fast       time.Duration
+	Acceptable time.Duration
+	Slow       time.Duration
+}
+
+// bucket returns the label of the bucket latency falls in
+func (b LatencyBuckets) bucket(latency time.Duration) string {
+	switch {
+	case latency <= b.Fast:
+		return LatencyFast
+	case latency <= b.Acceptable:
+		return LatencyAcceptable
+	case latency <= b.Slow:
+		return LatencySlow
+	default:
+		return LatencyCritical
+	}
+}
+
+// Device types returned by ClassifyDevice.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// ClassifyDevice is the default Options.DeviceClassifier. It guesses the device type of a User-Agent with
+// substring heuristics, returning one of the Device constants, or "" for an empty User-Agent.
+func ClassifyDevice(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+
+	ua := strings.ToLower(userAgent)
+	switch {
+	case containsAny(ua, "bot", "crawler", "spider", "slurp", "facebookexternalhit"):
+		return DeviceBot
+	case containsAny(ua, "ipad", "tablet", "kindle", "silk/", "playbook") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		return DeviceTablet
+	case containsAny(ua, "mobi", "iphone", "ipod", "android", "windows phone", "blackberry"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType returns the lower cased media type of a Content-Type header, without parameters
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// requestKind classifies a request Content-Type into one of the RequestKind constants
+func requestKind(contentType string) string {
+	mt := mediaType(contentType)
+
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return RequestKindJSON
+	case mt == "application/x-www-form-urlencoded":
+		return RequestKindForm
+	case strings.HasPrefix(mt, "multipart/"):
+		return RequestKindMultipart
+	default:
+		return RequestKindOther
+	}
+}
+
+// responseFormat classifies a response Content-Type into one of the ResponseFormat constants, or ""
+// when the header is empty
+func responseFormat(contentType string) string {
+	mt := mediaType(contentType)
+
+	switch {
+	case mt == "":
+		return ""
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return ResponseFormatJSON
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		return ResponseFormatXML
+	case mt == "text/html":
+		return ResponseFormatHTML
+	case strings.HasPrefix(mt, "text/"):
+		return ResponseFormatText
+	default:
+		return ResponseFormatBinary
+	}
+}
+
+// apiVersion returns the version token of the first vendor media type of an Accept header, such as
+// "v2" for application/vnd.myapi.v2+json, or of its version parameter (application/json; version=2),
+// or "" when none is versioned
+func apiVersion(accept string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		parts := strings.Split(mediaRange, ";")
+
+		mt := strings.ToLower(strings.TrimSpace(parts[0]))
+		if i := strings.IndexByte(mt, '/'); i >= 0 && strings.HasPrefix(mt[i+1:], "vnd.") {
+			subtype := mt[i+1:]
+			if j := strings.IndexByte(subtype, '+'); j >= 0 {
+				subtype = subtype[:j]
+			}
+			for _, token := range strings.Split(subtype, ".") {
+				if isVersionToken(token) {
+					return token
+				}
+			}
+		}
+
+		for _, param := range parts[1:] {
+			name, value := param, ""
+			if k := strings.IndexByte(param, '='); k >= 0 {
+				name, value = param[:k], strings.Trim(strings.TrimSpace(param[k+1:]), `"`)
+			}
+			if strings.EqualFold(strings.TrimSpace(name), "version") && value != "" {
+				if !isVersionToken(value) {
+					value = "v" + value
+				}
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// isVersionToken reports whether token is a "v" followed by digits
+func isVersionToken(token string) bool {
+	if len(token) < 2 || token[0] != 'v' {
+		return false
+	}
+	for _, r := range token[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// authScheme returns the scheme of an Authorization header (e.g. "Bearer"), never the credentials,
+// or "none" when the header is empty
+func authScheme(authorization string) string {
+	fields := strings.Fields(authorization)
+	if len(fields) == 0 {
+		return "none"
+	}
+	return fields[0]
+}
+
+// preferredLanguage returns the language of an Accept-Language header with the highest quality,
+// the first one winning ties. Wildcards and languages with a zero quality are ignored.
+func preferredLanguage(acceptLanguage string) string {
+	best, bestQ := "", 0.0
+
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		parts := strings.Split(item, ";")
+		tag := strings.TrimSpace(parts[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				value, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					value = 0
+				}
+				q = value
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+
+	return best
+}
+
+// isMutation reports whether method changes server state (POST, PUT, PATCH and DELETE)
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package echozap
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestKind(t *testing.T) {
+	tests := map[string]string{
+		"application/json":                  RequestKindJSON,
+		"application/json; charset=UTF-8":   RequestKindJSON,
+		"application/vnd.api+json":          RequestKindJSON,
+		"application/x-www-form-urlencoded": RequestKindForm,
+		"multipart/form-data; boundary=xyz": RequestKindMultipart,
+		"text/plain":                        RequestKindOther,
+		"":                                  RequestKindOther,
+	}
+
+	for contentType, expected := range tests {
+		assert.Equal(t, expected, requestKind(contentType), contentType)
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	tests := map[string]string{
+		echo.MIMEApplicationJSONCharsetUTF8: ResponseFormatJSON,
+		"application/problem+json":          ResponseFormatJSON,
+		echo.MIMEApplicationXMLCharsetUTF8:  ResponseFormatXML,
+		echo.MIMETextXML:                    ResponseFormatXML,
+		"application/atom+xml":              ResponseFormatXML,
+		echo.MIMETextHTMLCharsetUTF8:        ResponseFormatHTML,
+		echo.MIMETextPlainCharsetUTF8:       ResponseFormatText,
+		"text/csv":                          ResponseFormatText,
+		echo.MIMEOctetStream:                ResponseFormatBinary,
+		"image/png":                         ResponseFormatBinary,
+		"":                                  "",
+	}
+
+	for contentType, expected := range tests {
+		assert.Equal(t, expected, responseFormat(contentType), contentType)
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	tests := map[string]string{
+		"application/vnd.myapi.v2+json":                   "v2",
+		"application/vnd.github.v3.raw+json":              "v3",
+		"text/html, application/vnd.myapi.v10+json;q=0.9": "v10",
+		"application/json; version=2":                     "v2",
+		"application/json; charset=utf-8; version=\"v4\"": "v4",
+		"application/vnd.myapi+json":                      "",
+		"application/json":                                "",
+		"":                                                "",
+	}
+
+	for accept, expected := range tests {
+		assert.Equal(t, expected, apiVersion(accept), accept)
+	}
+}
+
+func TestAuthScheme(t *testing.T) {
+	assert.Equal(t, "Bearer", authScheme("Bearer eyJhbGciOiJIUzI1NiJ9.e30.sig"))
+	assert.Equal(t, "Basic", authScheme("Basic dXNlcjpwYXNz"))
+	assert.Equal(t, "none", authScheme(""))
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := map[string]string{
+		"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5": "fr-CH",
+		"en;q=0.5, en-US;q=0.9, fr":                    "fr",
+		"de;q=0.8, en-US;q=0.8":                        "de",
+		"*, pt-BR;q=0.1":                               "pt-BR",
+		"en;q=0, es;q=bad":                             "",
+		"":                                             "",
+	}
+
+	for header, expected := range tests {
+		assert.Equal(t, expected, preferredLanguage(header), header)
+	}
+}
+
+func TestIsMutation(t *testing.T) {
+	tests := map[string]bool{
+		http.MethodPost:    true,
+		http.MethodPut:     true,
+		http.MethodPatch:   true,
+		http.MethodDelete:  true,
+		http.MethodGet:     false,
+		http.MethodHead:    false,
+		http.MethodOptions: false,
+	}
+
+	for method, expected := range tests {
+		assert.Equal(t, expected, isMutation(method), method)
+	}
+}
+
+func TestClassifyDevice(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 13_2 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148":             DeviceMobile,
+		"Mozilla/5.0 (Linux; Android 10; Pixel 3) AppleWebKit/537.36 Chrome/78.0 Mobile Safari/537.36":          DeviceMobile,
+		"Mozilla/5.0 (iPad; CPU OS 13_2 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148":                      DeviceTablet,
+		"Mozilla/5.0 (Linux; Android 9; SM-T820) AppleWebKit/537.36 Chrome/78.0 Safari/537.36":                  DeviceTablet,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_1) AppleWebKit/537.36 Chrome/78.0 Safari/537.36":          DeviceDesktop,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:70.0) Gecko/20100101 Firefox/70.0":                        DeviceDesktop,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                              DeviceBot,
+		"Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X) AppleWebKit/537.36 Chrome/78.0 Mobile Safari (Googlebot)": DeviceBot,
+		"": "",
+	}
+
+	for ua, expected := range tests {
+		assert.Equal(t, expected, ClassifyDevice(ua), ua)
+	}
+}
@@ -0,0 +1,93 @@
+package echozap
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// coalesceKey identifies entries considered identical by the coalescer
+type coalesceKey struct {
+	method string
+	route  string
+	status int
+}
+
+// coalescedEntry is the first entry seen for a key and the number of entries collapsed into it
+type coalescedEntry struct {
+	logger *zap.Logger
+	level  zapcore.Level
+	msg    string
+	fields []zapcore.Field
+	count  int
+}
+
+// coalescer collapses identical entries seen within a window into a single entry with a "count" field.
+// The first entry of a key opens the window, the entry is written once the window closes.
+type coalescer struct {
+	options *Options
+	window  time.Duration
+
+	mu      sync.Mutex
+	pending map[coalesceKey]*coalescedEntry
+}
+
+// newCoalescer returns a coalescer writing entries through writeEntry once window elapses
+func newCoalescer(options *Options, window time.Duration) *coalescer {
+	return &coalescer{
+		options: options,
+		window:  window,
+		pending: make(map[coalesceKey]*coalescedEntry),
+	}
+}
+
+// add records an entry, writing it once the window of its key closes. Its fields are frozen first, as
+// objects reading the echo.Context when encoded would otherwise log the request it was recycled for.
+func (co *coalescer) add(key coalesceKey, logger *zap.Logger, level zapcore.Level, msg string, fields []zapcore.Field) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+
+	if entry, ok := co.pending[key]; ok {
+		entry.count++
+		return
+	}
+
+	co.pending[key] = &coalescedEntry{logger: logger, level: level, msg: msg, fields: freezeFields(fields), count: 1}
+	time.AfterFunc(co.window, func() {
+		co.flush(key)
+	})
+}
+
+// flush writes the pending entry of key
+func (co *coalescer) flush(key coalesceKey) {
+	co.mu.Lock()
+	entry := co.pending[key]
+	delete(co.pending, key)
+	co.mu.Unlock()
+
+	if entry == nil {
+		return
+	}
+
+	writeEntry(co.options, entry.logger, entry.level, entry.msg, appendTopLevel(entry.fields, zap.Int("count", entry.count)))
+}
+
+// freezeFields replaces the fields encoded lazily, objects, arrays and stringers, with plain values
+// encoded now
+func freezeFields(fields []zapcore.Field) []zapcore.Field {
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.StringerType:
+			enc := zapcore.NewMapObjectEncoder()
+			field.AddTo(enc)
+			if value, ok := enc.Fields[field.Key].(string); ok {
+				fields[i] = zap.String(field.Key, value)
+			} else {
+				fields[i] = zap.Reflect(field.Key, enc.Fields[field.Key])
+			}
+		}
+	}
+	return fields
+}
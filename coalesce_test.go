@@ -0,0 +1,81 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerCoalesce(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), CoalesceWindow: 50 * time.Millisecond}))
+	e.GET("/health", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/ready", func(c echo.Context) error {
+		return c.String(http.StatusServiceUnavailable, "")
+	})
+
+	for _, path := range []string{"/health", "/health", "/ready", "/health"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 0, logs.Len())
+
+	deadline := time.Now().Add(time.Second)
+	for logs.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	counts := map[string]interface{}{}
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		counts[fields["request"].(string)] = fields["count"]
+	}
+	assert.Equal(t, map[string]interface{}{"GET /health": int64(3), "GET /ready": int64(1)}, counts)
+}
+
+func TestZapLoggerCoalesceRecycledContext(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), Preset: PresetGCP, LogPathParams: true, CoalesceWindow: 50 * time.Millisecond}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.POST("/items/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/abc", nil))
+
+	deadline := time.Now().Add(time.Second)
+	for logs.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, 2, logs.Len())
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		httpRequest := fields["httpRequest"].(map[string]interface{})
+		switch httpRequest["requestMethod"] {
+		case http.MethodGet:
+			assert.Equal(t, "/users/42", httpRequest["requestUrl"])
+			assert.Equal(t, map[string]interface{}{"id": "42"}, fields["path_params"])
+		case http.MethodPost:
+			assert.Equal(t, "/items/abc", httpRequest["requestUrl"])
+			assert.Equal(t, map[string]interface{}{"id": "abc"}, fields["path_params"])
+		default:
+			t.Fatalf("unexpected method %v", httpRequest["requestMethod"])
+		}
+	}
+}
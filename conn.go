@@ -0,0 +1,76 @@
+package echozap
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// connReusedKey is the standard context key WithConnReused stores the flag under
+type connReusedKey struct{}
+
+// WithConnReused returns a copy of ctx telling whether the request arrived on a connection that already
+// served requests. The standard library doesn't expose it to handlers, so it is set by the code owning
+// the connections, or tracked by a ConnTracker.
+func WithConnReused(ctx context.Context, reused bool) context.Context {
+	return context.WithValue(ctx, connReusedKey{}, reused)
+}
+
+// ConnTracker counts the requests served on each open connection, keyed by remote address, to tell
+// whether a request reused a connection. Wire its ConnState method in the http.Server:
+//
+//	tracker := echozap.NewConnTracker()
+//	e.Server.ConnState = tracker.ConnState
+//
+// HTTP/2 connections multiplex requests and are only counted once.
+type ConnTracker struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// NewConnTracker returns an empty ConnTracker
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{requests: make(map[string]int)}
+}
+
+// ConnState is an http.Server ConnState hook
+func (t *ConnTracker) ConnState(conn net.Conn, state http.ConnState) {
+	addr := conn.RemoteAddr().String()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch state {
+	case http.StateNew:
+		t.requests[addr] = 0
+	case http.StateActive:
+		t.requests[addr]++
+	case http.StateHijacked, http.StateClosed:
+		delete(t.requests, addr)
+	}
+}
+
+// reused reports whether the connection from remoteAddr served requests before the current one, and
+// whether the connection is known
+func (t *ConnTracker) reused(remoteAddr string) (bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests, ok := t.requests[remoteAddr]
+	return requests > 1, ok
+}
+
+// connReused tells whether the request reused a connection, from the context flag or else from tracker
+func connReused(c echo.Context, tracker *ConnTracker) (bool, bool) {
+	req := c.Request()
+	if reused, ok := req.Context().Value(connReusedKey{}).(bool); ok {
+		return reused, true
+	}
+	if tracker == nil {
+		return false, false
+	}
+	return tracker.reused(req.RemoteAddr)
+}
@@ -0,0 +1,71 @@
+package echozap
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerConnReused(t *testing.T) {
+	tests := map[string]interface{}{
+		"true":  true,
+		"false": false,
+		"":      nil,
+	}
+
+	for flag, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		if flag != "" {
+			req = req.WithContext(WithConnReused(req.Context(), flag == "true"))
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogConnReused: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["conn_reused"], flag)
+	}
+}
+
+func TestConnTracker(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	tracker := NewConnTracker()
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogConnReused: true, ConnTracker: tracker}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	server := httptest.NewUnstartedServer(e)
+	server.Config.ConnState = tracker.ConnState
+	server.Start()
+	defer server.Close()
+
+	client := server.Client()
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, false, logs.All()[0].ContextMap()["conn_reused"])
+	assert.Equal(t, true, logs.All()[1].ContextMap()["conn_reused"])
+}
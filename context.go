@@ -0,0 +1,154 @@
+package echozap
+
+import (
+	"context"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerContextKey is the standard context key Options.InjectContextLogger stores the logger under
+type loggerContextKey struct{}
+
+const (
+	// statusSourceKey is the context key MarkSource stores the component name under
+	statusSourceKey = "_echozap_status_source_"
+	// routeGroupKey is the context key RouteGroup stores the group prefix under
+	routeGroupKey = "_echozap_route_group_"
+	// levelKey is the context key SetLevel stores the level override under
+	levelKey = "_echozap_level_"
+	// scopedLoggerKey is the context key the scoped logger is stored under for FromContext, whatever
+	// the CustomLoggerKey
+	scopedLoggerKey = "_echozap_scoped_logger_"
+	// fieldsKeyKey is the context key the middleware records its CustomFieldsKey under for AddFields
+	fieldsKeyKey = "_echozap_fields_key_"
+	// fieldsLockKey is the context key the middleware stores the lock of the custom fields under
+	fieldsLockKey = "_echozap_fields_lock_"
+	// requestIDKey is the context key the request id generated with Options.GenerateRequestID is stored under
+	requestIDKey = "_echozap_request_id_"
+)
+
+// MarkSource records name as the component that set the response status. Middleware and handlers
+// call it when they commit a status; the last call wins and is logged as "status_source" when
+// Options.LogStatusSource is set.
+func MarkSource(c echo.Context, name string) {
+	c.Set(statusSourceKey, name)
+}
+
+// statusSource returns the name recorded by MarkSource, if any
+func statusSource(c echo.Context) string {
+	name, _ := c.Get(statusSourceKey).(string)
+	return name
+}
+
+// RouteGroup returns a middleware recording prefix as the route group of the requests it handles,
+// logged as "route_group" when Options.LogRouteGroup is set. Register it on the group:
+//
+//	v1 := e.Group("/api/v1", echozap.RouteGroup("/api/v1"))
+func RouteGroup(prefix string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(routeGroupKey, prefix)
+			return next(c)
+		}
+	}
+}
+
+// routeGroup returns the prefix recorded by RouteGroup, if any
+func routeGroup(c echo.Context) string {
+	prefix, _ := c.Get(routeGroupKey).(string)
+	return prefix
+}
+
+// SetLevel overrides the level of the entry logged for the current request, regardless of its
+// status. Handlers call it, e.g. with zapcore.WarnLevel when a deprecated endpoint is hit; the last
+// call wins.
+func SetLevel(c echo.Context, level zapcore.Level) {
+	c.Set(levelKey, level)
+}
+
+// levelOverride returns the level recorded by SetLevel, if any
+func levelOverride(c echo.Context) (zapcore.Level, bool) {
+	level, ok := c.Get(levelKey).(zapcore.Level)
+	return level, ok
+}
+
+// RequestID returns the id of the current request: the one generated with Options.GenerateRequestID, or
+// else the X-Request-ID request header, or else the X-Request-ID response header.
+func RequestID(c echo.Context) string {
+	return requestID(c)
+}
+
+// AddFields appends fields to the custom fields logged with the access-log entry of the current
+// request, so middleware, handlers and business logic can each contribute their own. The slice already
+// stored is never modified in place. Within a request served by ZapLogger, AddFields and SetFields may
+// be called concurrently, e.g. by goroutines fanning out the work of a handler.
+func AddFields(c echo.Context, fields ...zapcore.Field) {
+	defer lockFields(c)()
+
+	current := GetFields(c)
+	merged := make([]zapcore.Field, 0, len(current)+len(fields))
+	merged = append(merged, current...)
+	c.Set(customFieldsKey(c), append(merged, fields...))
+}
+
+// SetFields replaces the custom fields logged with the access-log entry of the current request
+func SetFields(c echo.Context, fields ...zapcore.Field) {
+	defer lockFields(c)()
+
+	c.Set(customFieldsKey(c), fields)
+}
+
+// lockFields locks the custom fields of the request, when served by the middleware, and returns the
+// function unlocking them
+func lockFields(c echo.Context) func() {
+	mu, ok := c.Get(fieldsLockKey).(*sync.Mutex)
+	if !ok {
+		return func() {}
+	}
+	mu.Lock()
+	return mu.Unlock
+}
+
+// GetFields returns the custom fields logged with the access-log entry of the current request
+func GetFields(c echo.Context) []zapcore.Field {
+	fields, _ := c.Get(customFieldsKey(c)).([]zapcore.Field)
+	return fields
+}
+
+// customFieldsKey returns the CustomFieldsKey of the middleware handling the request, or else the
+// default set by SetDefaultKeys
+func customFieldsKey(c echo.Context) string {
+	if key, ok := c.Get(fieldsKeyKey).(string); ok {
+		return key
+	}
+	key, _ := defaultKeys()
+	return key
+}
+
+// FromContext returns the request logger injected by the middleware with Options.InjectScopedLogger, or
+// else stored in the request context.Context with Options.InjectContextLogger. A no-op logger is
+// returned when there is none, so handlers can always log.
+func FromContext(c echo.Context) *zap.Logger {
+	if logger, ok := c.Get(scopedLoggerKey).(*zap.Logger); ok {
+		return logger
+	}
+	return LoggerFromContext(c.Request().Context())
+}
+
+// LoggerFromContext returns the logger stored in the request context.Context when
+// Options.InjectContextLogger is set, letting code unaware of echo log with the request scope. A no-op
+// logger is returned when none is stored.
+func LoggerFromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.NewNop()
+}
+
+// withLogger returns a copy of ctx carrying logger
+func withLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
@@ -0,0 +1,337 @@
+package echozap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMarkSource(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogStatusSource: true}))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			MarkSource(c, "auth")
+			return next(c)
+		}
+	})
+	e.GET("/", func(c echo.Context) error {
+		MarkSource(c, "handler")
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/unmarked", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unmarked", nil))
+
+	assert.Equal(t, "handler", logs.All()[0].ContextMap()["status_source"])
+	assert.Equal(t, "auth", logs.All()[1].ContextMap()["status_source"])
+}
+
+func TestZapLoggerInjectScopedLogger(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderXRequestID, "abc")
+			return next(c)
+		}
+	})
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), InjectScopedLogger: true}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		c.Get(DefaultCustomLoggerKey).(*zap.Logger).Info("loading user")
+		return c.String(http.StatusOK, "")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	assert.Equal(t, 2, logs.Len())
+
+	for _, entry := range logs.All() {
+		logFields := entry.ContextMap()
+		assert.Equal(t, "abc", logFields["request_id"])
+		assert.Equal(t, http.MethodGet, logFields["method"])
+		assert.Equal(t, "/users/:id", logFields["route"])
+	}
+
+	assert.Equal(t, "loading user", logs.All()[0].Message)
+	assert.Len(t, logs.All()[1].Context, 10)
+}
+
+func TestRouteGroup(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogRouteGroup: true}))
+
+	v1 := e.Group("/api/v1", RouteGroup("/api/v1"))
+	v1.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/health", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, "/api/v1", logs.All()[0].ContextMap()["route_group"])
+	assert.NotContains(t, logs.All()[1].ContextMap(), "route_group")
+}
+
+func TestSetLevel(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs)}))
+	e.GET("/deprecated", func(c echo.Context) error {
+		SetLevel(c, zapcore.WarnLevel)
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/deprecated", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	assert.Equal(t, zapcore.WarnLevel, logs.All()[0].Level)
+	assert.Equal(t, "Success: OK", logs.All()[0].Message)
+	assert.Equal(t, zapcore.InfoLevel, logs.All()[1].Level)
+}
+
+func TestZapLoggerInjectContextLogger(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderXRequestID, "abc")
+			return next(c)
+		}
+	})
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), InjectScopedLogger: true, InjectContextLogger: true}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		loadUser(c.Request().Context())
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "loading user", logs.All()[0].Message)
+	assert.Equal(t, "abc", logs.All()[0].ContextMap()["request_id"])
+	assert.Equal(t, "/users/:id", logs.All()[0].ContextMap()["route"])
+}
+
+// loadUser stands for code that only receives a context.Context
+func loadUser(ctx context.Context) {
+	LoggerFromContext(ctx).Info("loading user")
+}
+
+func TestLoggerFromContextWithoutLogger(t *testing.T) {
+	assert.NotNil(t, LoggerFromContext(context.Background()))
+}
+
+func TestFromContext(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:              zap.New(obs),
+		CustomLoggerKey:     "my-logger",
+		InjectScopedLogger:  true,
+		InjectContextLogger: true,
+	}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		FromContext(c).Info("from echo context")
+		LoggerFromContext(c.Request().Context()).Info("from context")
+		return c.String(http.StatusOK, "")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, 3, logs.Len())
+
+	for _, entry := range logs.All()[:2] {
+		logFields := entry.ContextMap()
+		assert.Equal(t, "abc", logFields["request_id"])
+		assert.Equal(t, "/users/:id", logFields["route"])
+		assert.Equal(t, "192.0.2.1", logFields["remote_ip"])
+	}
+
+	// remote_ip is not duplicated on the access log
+	count := 0
+	for _, field := range logs.All()[2].Context {
+		if field.Key == "remote_ip" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.NotNil(t, FromContext(c))
+}
+
+func TestAddFields(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), CustomFieldsKey: "my-fields"}))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			AddFields(c, zap.String("user", "bob"))
+			return next(c)
+		}
+	})
+	e.GET("/", func(c echo.Context) error {
+		AddFields(c, zap.Int("items", 3), zap.String("tenant", "acme"))
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/reset", func(c echo.Context) error {
+		SetFields(c, zap.String("tenant", "acme"))
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reset", nil))
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "bob", logFields["user"])
+	assert.Equal(t, int64(3), logFields["items"])
+	assert.Equal(t, "acme", logFields["tenant"])
+
+	logFields = logs.All()[1].ContextMap()
+	assert.NotContains(t, logFields, "user")
+	assert.Equal(t, "acme", logFields["tenant"])
+}
+
+// yieldingContext yields the processor before storing values, for concurrent calls to interleave
+type yieldingContext struct {
+	echo.Context
+}
+
+func (c yieldingContext) Set(key string, val interface{}) {
+	runtime.Gosched()
+	c.Context.Set(key, val)
+}
+
+func TestAddFieldsConcurrently(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs)}))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(yieldingContext{c})
+		}
+	})
+	e.GET("/", func(c echo.Context) error {
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				AddFields(c, zap.Int(strconv.Itoa(i), i))
+			}(i)
+		}
+		wg.Wait()
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	logFields := logs.All()[0].ContextMap()
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, int64(i), logFields[strconv.Itoa(i)])
+	}
+}
+
+func TestAddFieldsDoesNotModifyStoredSlice(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	stored := make([]zapcore.Field, 1, 2)
+	stored[0] = zap.String("a", "1")
+	c.Set(DefaultCustomFieldsKey, stored)
+
+	AddFields(c, zap.String("b", "2"))
+
+	assert.Len(t, GetFields(c), 2)
+	assert.Equal(t, "a", stored[:2][0].Key)
+	assert.Equal(t, "", stored[:2][1].Key)
+}
+
+func TestGenerateRequestID(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	var handled string
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:             zap.New(obs),
+		GenerateRequestID:  true,
+		InjectScopedLogger: true,
+		RequestIDGenerator: func() string { return "generated" },
+	}))
+	e.GET("/", func(c echo.Context) error {
+		handled = RequestID(c)
+		FromContext(c).Info("handling")
+		return c.String(http.StatusOK, "")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "generated", handled)
+	assert.Equal(t, "generated", rec.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "generated", logs.All()[0].ContextMap()["request_id"])
+	assert.Equal(t, "generated", logs.All()[1].ContextMap()["request_id"])
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, "abc", handled)
+	assert.Empty(t, rec.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "abc", logs.All()[3].ContextMap()["request_id"])
+}
+
+func TestGenerateRequestIDDefault(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), GenerateRequestID: true}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, id, 36)
+	assert.Equal(t, id, logs.All()[0].ContextMap()["request_id"])
+	assert.NotContains(t, logs.All()[0].ContextMap(), "request_id_mismatch")
+}
@@ -0,0 +1,52 @@
+package echozap
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultCorrelationIDHeader is the response header the correlation id is written to.
+const DefaultCorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationSource returns a correlation id for a request, or "" when it has none.
+type CorrelationSource func(c echo.Context) string
+
+// CorrelationFromTraceParent uses the trace id of the W3C traceparent request header.
+func CorrelationFromTraceParent(c echo.Context) string {
+	tp, ok := parseTraceParent(c.Request().Header.Get(headerTraceParent))
+	if !ok {
+		return ""
+	}
+	return tp.traceID
+}
+
+// CorrelationFromHeader uses the value of a request header.
+func CorrelationFromHeader(name string) CorrelationSource {
+	return func(c echo.Context) string {
+		return c.Request().Header.Get(name)
+	}
+}
+
+// CorrelationGenerate generates a random UUID.
+func CorrelationGenerate(echo.Context) string {
+	return newUUID()
+}
+
+// DefaultCorrelationSources returns the sources used when Options.CorrelationSources is empty: the trace
+// id, then the X-Request-ID header, then a generated UUID.
+func DefaultCorrelationSources() []CorrelationSource {
+	return []CorrelationSource{
+		CorrelationFromTraceParent,
+		CorrelationFromHeader(echo.HeaderXRequestID),
+		CorrelationGenerate,
+	}
+}
+
+// correlationID returns the id of the first source that has one
+func correlationID(c echo.Context, sources []CorrelationSource) string {
+	for _, source := range sources {
+		if id := source(c); id != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerCorrelationID(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		id      string
+	}{
+		{
+			name:    "trace id",
+			headers: map[string]string{"traceparent": traceParent, echo.HeaderXRequestID: "req-1"},
+			id:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "request id",
+			headers: map[string]string{"traceparent": "garbage", echo.HeaderXRequestID: "req-1"},
+			id:      "req-1",
+		},
+		{
+			name: "generated",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/something", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := func(c echo.Context) error {
+				return c.String(http.StatusOK, "")
+			}
+
+			obs, logs := observer.New(zap.DebugLevel)
+
+			err := ZapLogger(&Options{Logger: zap.New(obs), LogCorrelationID: true})(h)(c)
+
+			assert.Nil(t, err)
+
+			id := logs.All()[0].ContextMap()["correlation_id"].(string)
+			if test.id != "" {
+				assert.Equal(t, test.id, id)
+			} else {
+				assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+			}
+			assert.Equal(t, id, rec.Header().Get(DefaultCorrelationIDHeader))
+		})
+	}
+}
+
+func TestZapLoggerCorrelationIDScopedLogger(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Get(DefaultCustomLoggerKey).(*zap.Logger).Info("handler")
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:             zap.New(obs),
+		LogCorrelationID:   true,
+		InjectScopedLogger: true,
+		CorrelationSources: []CorrelationSource{CorrelationFromHeader("X-Missing"), func(echo.Context) string { return "fixed" }},
+	})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "fixed", logs.All()[0].ContextMap()["correlation_id"])
+	assert.Equal(t, "fixed", logs.All()[1].ContextMap()["correlation_id"])
+}
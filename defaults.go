@@ -0,0 +1,35 @@
+package echozap
+
+import "sync"
+
+var (
+	defaultKeysMu    sync.RWMutex
+	defaultFieldsKey = DefaultCustomFieldsKey
+	defaultLoggerKey = DefaultCustomLoggerKey
+)
+
+// SetDefaultKeys changes the keys used by middleware created afterwards when Options leaves
+// CustomFieldsKey or CustomLoggerKey empty. An empty key restores the package default. It is safe
+// for concurrent use.
+func SetDefaultKeys(fieldsKey, loggerKey string) {
+	if fieldsKey == "" {
+		fieldsKey = DefaultCustomFieldsKey
+	}
+	if loggerKey == "" {
+		loggerKey = DefaultCustomLoggerKey
+	}
+
+	defaultKeysMu.Lock()
+	defer defaultKeysMu.Unlock()
+
+	defaultFieldsKey = fieldsKey
+	defaultLoggerKey = loggerKey
+}
+
+// defaultKeys returns the keys set by SetDefaultKeys
+func defaultKeys() (fieldsKey, loggerKey string) {
+	defaultKeysMu.RLock()
+	defer defaultKeysMu.RUnlock()
+
+	return defaultFieldsKey, defaultLoggerKey
+}
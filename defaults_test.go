@@ -0,0 +1,72 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSetDefaultKeys(t *testing.T) {
+	SetDefaultKeys("fields", "logger")
+	defer SetDefaultKeys("", "")
+
+	configured := &Options{Logger: zap.NewNop()}
+	ZapLogger(configured)
+	assert.Equal(t, "fields", configured.CustomFieldsKey)
+	assert.Equal(t, "logger", configured.CustomLoggerKey)
+
+	explicit := &Options{Logger: zap.NewNop(), CustomFieldsKey: "mine"}
+	ZapLogger(explicit)
+	assert.Equal(t, "mine", explicit.CustomFieldsKey)
+	assert.Equal(t, "logger", explicit.CustomLoggerKey)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	h := func(c echo.Context) error {
+		c.Set("fields", []zapcore.Field{zap.String("user", "bob")})
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", logs.All()[0].ContextMap()["user"])
+}
+
+func TestSetDefaultKeysReset(t *testing.T) {
+	SetDefaultKeys("fields", "logger")
+	SetDefaultKeys("", "")
+
+	fieldsKey, loggerKey := defaultKeys()
+	assert.Equal(t, DefaultCustomFieldsKey, fieldsKey)
+	assert.Equal(t, DefaultCustomLoggerKey, loggerKey)
+}
+
+func TestSetDefaultKeysConcurrent(t *testing.T) {
+	defer SetDefaultKeys("", "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetDefaultKeys("fields", "logger")
+		}()
+		go func() {
+			defer wg.Done()
+			ZapLogger(&Options{Logger: zap.NewNop()})
+		}()
+	}
+	wg.Wait()
+}
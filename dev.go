@@ -0,0 +1,59 @@
+package echozap
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapLoggerDev is a middleware like ZapLogger meant for local development. It builds a console
+// encoder logger with colored levels that writes to stdout, and logs each request as a compact
+// line such as "200 GET /foo 1.2ms". Any Logger set in options is replaced.
+func ZapLoggerDev(options *Options) echo.MiddlewareFunc {
+	return zapLoggerDev(zapcore.Lock(os.Stdout), options)
+}
+
+// zapLoggerDev wires a development logger writing to ws into the middleware
+func zapLoggerDev(ws zapcore.WriteSyncer, options *Options) echo.MiddlewareFunc {
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+
+	opts.Logger = newDevLogger(ws)
+	opts.console = true
+
+	return ZapLogger(&opts)
+}
+
+// newDevLogger returns a colored console logger writing to ws
+func newDevLogger(ws zapcore.WriteSyncer) *zap.Logger {
+	return zap.New(zapcore.NewCore(newDevEncoder(), ws, zapcore.DebugLevel))
+}
+
+// newDevEncoder returns a console encoder with colored levels
+func newDevEncoder() zapcore.Encoder {
+	config := zap.NewDevelopmentEncoderConfig()
+	config.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return zapcore.NewConsoleEncoder(config)
+}
+
+// formatLatency formats d rounded to one decimal in its most significant unit (e.g. 1.2ms)
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.1fms", milliseconds(d))
+	case d >= time.Microsecond:
+		return fmt.Sprintf("%dµs", d/time.Microsecond)
+	default:
+		return fmt.Sprintf("%dns", d)
+	}
+}
@@ -0,0 +1,69 @@
+package echozap
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestZapLoggerDev(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	var buf bytes.Buffer
+	err := zapLoggerDev(zapcore.AddSync(&buf), nil)(h)(c)
+
+	assert.Nil(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "200 GET /foo ")
+	assert.Contains(t, out, "INFO")
+	assert.NotContains(t, out, "Success: OK")
+}
+
+func TestZapLoggerDevCompactLatency(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	var buf bytes.Buffer
+	err := zapLoggerDev(zapcore.AddSync(&buf), &Options{
+		Clock:          stepClock(time.Now(), 1234567*time.Nanosecond),
+		CompactLatency: true,
+	})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `"latency": "1.2ms"`)
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := map[time.Duration]string{
+		1234567 * time.Nanosecond: "1.2ms",
+		340123 * time.Nanosecond:  "340µs",
+		2100 * time.Millisecond:   "2.1s",
+		90 * time.Second:          "90.0s",
+		999 * time.Nanosecond:     "999ns",
+		time.Millisecond:          "1.0ms",
+	}
+
+	for d, expected := range tests {
+		assert.Equal(t, expected, formatLatency(d), d.String())
+	}
+}
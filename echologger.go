@@ -0,0 +1,208 @@
+package echozap
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"go.uber.org/zap"
+)
+
+// echoLogger is an echo.Logger writing to a zap logger
+type echoLogger struct {
+	logger *zap.Logger
+	sugar  *zap.SugaredLogger
+	level  uint32
+
+	mu     sync.RWMutex
+	prefix string
+}
+
+// NewEchoLogger returns an echo.Logger writing to logger, so the logs of echo itself share the format
+// of the access log. Levels map to the zap levels of the same name, Print to Info; SetLevel filters
+// entries in addition to the zap core. The header and output set on it are ignored, and Output returns
+// a writer logging each write at Error. Set it before the server starts and rebuild the standard
+// logger, which echo creates from the previous one:
+//
+//	e.Logger = echozap.NewEchoLogger(zapLogger)
+//	e.StdLogger = stdlog.New(e.Logger.Output(), "", 0)
+func NewEchoLogger(logger *zap.Logger) echo.Logger {
+	logger = logger.WithOptions(zap.AddCallerSkip(1))
+	return &echoLogger{logger: logger, sugar: logger.Sugar(), level: uint32(log.DEBUG)}
+}
+
+// Output returns a writer logging each write at Error, as echo's standard logger does for server errors
+func (l *echoLogger) Output() io.Writer {
+	return errorWriter{logger: l.logger}
+}
+
+// SetOutput is ignored, entries are written to the outputs of the zap logger
+func (l *echoLogger) SetOutput(io.Writer) {}
+
+func (l *echoLogger) Prefix() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.prefix
+}
+
+// SetPrefix records p, returned by Prefix. Use zap.Logger.Named to name the zap logger instead.
+func (l *echoLogger) SetPrefix(p string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prefix = p
+}
+
+func (l *echoLogger) Level() log.Lvl {
+	return log.Lvl(atomic.LoadUint32(&l.level))
+}
+
+func (l *echoLogger) SetLevel(v log.Lvl) {
+	atomic.StoreUint32(&l.level, uint32(v))
+}
+
+// SetHeader is ignored, the layout is that of the zap encoder
+func (l *echoLogger) SetHeader(string) {}
+
+// enabled reports whether entries of level pass the level set with SetLevel
+func (l *echoLogger) enabled(level log.Lvl) bool {
+	return level >= l.Level()
+}
+
+func (l *echoLogger) Print(i ...interface{}) {
+	l.sugar.Info(i...)
+}
+
+func (l *echoLogger) Printf(format string, args ...interface{}) {
+	l.sugar.Infof(format, args...)
+}
+
+func (l *echoLogger) Printj(j log.JSON) {
+	l.sugar.Infow("", jsonFields(j)...)
+}
+
+func (l *echoLogger) Debug(i ...interface{}) {
+	if l.enabled(log.DEBUG) {
+		l.sugar.Debug(i...)
+	}
+}
+
+func (l *echoLogger) Debugf(format string, args ...interface{}) {
+	if l.enabled(log.DEBUG) {
+		l.sugar.Debugf(format, args...)
+	}
+}
+
+func (l *echoLogger) Debugj(j log.JSON) {
+	if l.enabled(log.DEBUG) {
+		l.sugar.Debugw("", jsonFields(j)...)
+	}
+}
+
+func (l *echoLogger) Info(i ...interface{}) {
+	if l.enabled(log.INFO) {
+		l.sugar.Info(i...)
+	}
+}
+
+func (l *echoLogger) Infof(format string, args ...interface{}) {
+	if l.enabled(log.INFO) {
+		l.sugar.Infof(format, args...)
+	}
+}
+
+func (l *echoLogger) Infoj(j log.JSON) {
+	if l.enabled(log.INFO) {
+		l.sugar.Infow("", jsonFields(j)...)
+	}
+}
+
+func (l *echoLogger) Warn(i ...interface{}) {
+	if l.enabled(log.WARN) {
+		l.sugar.Warn(i...)
+	}
+}
+
+func (l *echoLogger) Warnf(format string, args ...interface{}) {
+	if l.enabled(log.WARN) {
+		l.sugar.Warnf(format, args...)
+	}
+}
+
+func (l *echoLogger) Warnj(j log.JSON) {
+	if l.enabled(log.WARN) {
+		l.sugar.Warnw("", jsonFields(j)...)
+	}
+}
+
+func (l *echoLogger) Error(i ...interface{}) {
+	if l.enabled(log.ERROR) {
+		l.sugar.Error(i...)
+	}
+}
+
+func (l *echoLogger) Errorf(format string, args ...interface{}) {
+	if l.enabled(log.ERROR) {
+		l.sugar.Errorf(format, args...)
+	}
+}
+
+func (l *echoLogger) Errorj(j log.JSON) {
+	if l.enabled(log.ERROR) {
+		l.sugar.Errorw("", jsonFields(j)...)
+	}
+}
+
+func (l *echoLogger) Fatal(i ...interface{}) {
+	l.sugar.Fatal(i...)
+}
+
+func (l *echoLogger) Fatalj(j log.JSON) {
+	l.sugar.Fatalw("", jsonFields(j)...)
+}
+
+func (l *echoLogger) Fatalf(format string, args ...interface{}) {
+	l.sugar.Fatalf(format, args...)
+}
+
+func (l *echoLogger) Panic(i ...interface{}) {
+	l.sugar.Panic(i...)
+}
+
+func (l *echoLogger) Panicj(j log.JSON) {
+	l.sugar.Panicw("", jsonFields(j)...)
+}
+
+func (l *echoLogger) Panicf(format string, args ...interface{}) {
+	l.sugar.Panicf(format, args...)
+}
+
+// jsonFields returns the entries of j as sugared key-value pairs, sorted by key
+func jsonFields(j log.JSON) []interface{} {
+	keys := make([]string, 0, len(j))
+	for key := range j {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		fields = append(fields, key, j[key])
+	}
+	return fields
+}
+
+// errorWriter logs each write at Error, without the trailing newline
+type errorWriter struct {
+	logger *zap.Logger
+}
+
+func (w errorWriter) Write(p []byte) (int, error) {
+	w.logger.Error(string(bytes.TrimRight(p, "\n")))
+	return len(p), nil
+}
@@ -0,0 +1,71 @@
+package echozap
+
+import (
+	stdlog "log"
+	"testing"
+
+	"github.com/labstack/gommon/log"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewEchoLogger(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	logger := NewEchoLogger(zap.New(obs))
+
+	logger.Print("started ", "server")
+	logger.Debugf("route %s", "/users")
+	logger.Warnj(log.JSON{"port": 8080, "addr": "localhost"})
+	logger.Error("shutdown")
+
+	entries := logs.All()
+	assert.Len(t, entries, 4)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, "started server", entries[0].Message)
+	assert.Equal(t, zapcore.DebugLevel, entries[1].Level)
+	assert.Equal(t, "route /users", entries[1].Message)
+	assert.Equal(t, zapcore.WarnLevel, entries[2].Level)
+	assert.Equal(t, map[string]interface{}{"addr": "localhost", "port": int64(8080)}, entries[2].ContextMap())
+	assert.Equal(t, zapcore.ErrorLevel, entries[3].Level)
+}
+
+func TestNewEchoLoggerSetLevel(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	logger := NewEchoLogger(zap.New(obs))
+
+	logger.SetLevel(log.WARN)
+	assert.Equal(t, log.WARN, logger.Level())
+
+	logger.Debug("dropped")
+	logger.Info("dropped")
+	logger.Print("kept")
+	logger.Warn("kept")
+	logger.Errorf("kept")
+
+	assert.Equal(t, 3, logs.Len())
+	assert.Equal(t, 0, logs.FilterMessage("dropped").Len())
+}
+
+func TestNewEchoLoggerPanic(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	logger := NewEchoLogger(zap.New(obs))
+
+	assert.Panics(t, func() { logger.Panicf("bad %s", "config") })
+	assert.Equal(t, zapcore.PanicLevel, logs.All()[0].Level)
+}
+
+func TestNewEchoLoggerOutput(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	logger := NewEchoLogger(zap.New(obs))
+
+	logger.SetPrefix("echo")
+	assert.Equal(t, "echo", logger.Prefix())
+
+	stdlog.New(logger.Output(), "", 0).Println("http: TLS handshake error")
+
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, zapcore.ErrorLevel, logs.All()[0].Level)
+	assert.Equal(t, "http: TLS handshake error", logs.All()[0].Message)
+}
@@ -0,0 +1,23 @@
+// Package echozaptest provides helpers to test code using the echozap middleware.
+package echozaptest
+
+import (
+	"github.com/dhillondeep/echozap"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// NewTestMiddleware returns the echozap middleware configured with options, which may be nil, logging
+// every level to the returned observed logs. Any Logger set in options is replaced.
+func NewTestMiddleware(options *echozap.Options) (echo.MiddlewareFunc, *observer.ObservedLogs) {
+	opts := echozap.Options{}
+	if options != nil {
+		opts = *options
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+	opts.Logger = zap.New(obs)
+
+	return echozap.ZapLogger(&opts), logs
+}
@@ -0,0 +1,46 @@
+package echozaptest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dhillondeep/echozap"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewTestMiddleware(t *testing.T) {
+	mw, logs := NewTestMiddleware(&echozap.Options{LogStatusClass: true})
+
+	e := echo.New()
+	e.Use(mw)
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusNotFound, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	assert.Equal(t, 1, logs.Len())
+
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.WarnLevel, entry.Level)
+	assert.Equal(t, "Client: Not Found", entry.Message)
+	assert.Equal(t, "GET /users/42", entry.ContextMap()["request"])
+	assert.Equal(t, int64(4), entry.ContextMap()["status_class"])
+}
+
+func TestNewTestMiddlewareWithoutOptions(t *testing.T) {
+	mw, logs := NewTestMiddleware(nil)
+
+	e := echo.New()
+	e.Use(mw)
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 1, logs.FilterMessage("Success: OK").Len())
+}
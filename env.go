@@ -0,0 +1,65 @@
+package echozap
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// EnvLogLevel is the environment variable read by ZapLoggerFromEnv for the level (e.g. "debug")
+	EnvLogLevel = "LOG_LEVEL"
+	// EnvLogFormat is the environment variable read by ZapLoggerFromEnv for the format ("json" or "console")
+	EnvLogFormat = "LOG_FORMAT"
+)
+
+// ZapLoggerFromEnv is a middleware like ZapLogger whose logger writes to stdout with the level and format
+// read from the LOG_LEVEL and LOG_FORMAT environment variables (default: info and json). Any Logger set in
+// options is replaced. When a variable is invalid, its default is used and the returned error describes
+// the problem; the middleware is always usable.
+func ZapLoggerFromEnv(options *Options) (echo.MiddlewareFunc, error) {
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+
+	logger, err := newEnvLogger(zapcore.Lock(os.Stdout), os.Getenv(EnvLogLevel), os.Getenv(EnvLogFormat))
+	opts.Logger = logger
+
+	return ZapLogger(&opts), err
+}
+
+// newEnvLogger builds a logger writing to ws from level and format values, falling back to the
+// defaults for invalid ones
+func newEnvLogger(ws zapcore.WriteSyncer, level, format string) (*zap.Logger, error) {
+	var errs []string
+
+	lvl := zapcore.InfoLevel
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			lvl = zapcore.InfoLevel
+			errs = append(errs, fmt.Sprintf("invalid %s %q", EnvLogLevel, level))
+		}
+	}
+
+	config := zap.NewProductionEncoderConfig()
+	encoder := zapcore.NewJSONEncoder(config)
+	switch strings.ToLower(format) {
+	case "", "json":
+	case "console":
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewConsoleEncoder(config)
+	default:
+		errs = append(errs, fmt.Sprintf("invalid %s %q", EnvLogFormat, format))
+	}
+
+	logger := zap.New(zapcore.NewCore(encoder, ws, lvl))
+	if len(errs) > 0 {
+		return logger, fmt.Errorf("echozap: %s", strings.Join(errs, ", "))
+	}
+	return logger, nil
+}
@@ -0,0 +1,58 @@
+package echozap
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewEnvLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newEnvLogger(zapcore.AddSync(&buf), "warn", "console")
+
+	assert.Nil(t, err)
+	assert.False(t, logger.Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, logger.Core().Enabled(zapcore.WarnLevel))
+
+	logger.Warn("hello")
+	assert.Contains(t, buf.String(), "\twarn\thello")
+	assert.False(t, strings.HasPrefix(buf.String(), "{"))
+}
+
+func TestNewEnvLoggerDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newEnvLogger(zapcore.AddSync(&buf), "", "")
+
+	assert.Nil(t, err)
+	assert.True(t, logger.Core().Enabled(zapcore.InfoLevel))
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	logger.Info("hello")
+	assert.True(t, strings.HasPrefix(buf.String(), "{"))
+}
+
+func TestNewEnvLoggerInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newEnvLogger(zapcore.AddSync(&buf), "loud", "xml")
+
+	assert.EqualError(t, err, `echozap: invalid LOG_LEVEL "loud", invalid LOG_FORMAT "xml"`)
+	assert.NotNil(t, logger)
+	assert.True(t, logger.Core().Enabled(zapcore.InfoLevel))
+
+	logger.Info("hello")
+	assert.True(t, strings.HasPrefix(buf.String(), "{"))
+}
+
+func TestZapLoggerFromEnv(t *testing.T) {
+	os.Setenv(EnvLogLevel, "verbose")
+	defer os.Unsetenv(EnvLogLevel)
+
+	middleware, err := ZapLoggerFromEnv(nil)
+
+	assert.Error(t, err)
+	assert.NotNil(t, middleware)
+}
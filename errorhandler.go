@@ -0,0 +1,113 @@
+package echozap
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorHandlerOption configures NewZapHTTPErrorHandler.
+type ErrorHandlerOption func(*errorHandlerConfig)
+
+// errorHandlerConfig is the configuration built by the ErrorHandlerOption values
+type errorHandlerConfig struct {
+	minStatus int
+	next      echo.HTTPErrorHandler
+}
+
+// WithMinStatus logs the errors resulting in this status and above (default: 500).
+func WithMinStatus(status int) ErrorHandlerOption {
+	return func(config *errorHandlerConfig) {
+		config.minStatus = status
+	}
+}
+
+// WithNextHandler delegates responding to next instead of the echo default error handler.
+func WithNextHandler(next echo.HTTPErrorHandler) ErrorHandlerOption {
+	return func(config *errorHandlerConfig) {
+		config.next = next
+	}
+}
+
+// NewZapHTTPErrorHandler returns an echo.HTTPErrorHandler logging errors, then delegating the response
+// to the echo default error handler. Entries carry the "error_chain" of the errors wrapped with the
+// errors.Unwrap convention, starting from the Internal error of an *echo.HTTPError, and the
+// "error_stack" of the innermost error with a StackTrace method, such as those of github.com/pkg/errors.
+// Server errors are logged at Error, client errors at Warn when WithMinStatus includes them.
+func NewZapHTTPErrorHandler(logger *zap.Logger, opts ...ErrorHandlerOption) echo.HTTPErrorHandler {
+	config := &errorHandlerConfig{minStatus: http.StatusInternalServerError}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(err error, c echo.Context) {
+		status := http.StatusInternalServerError
+		if he, ok := err.(*echo.HTTPError); ok {
+			status = he.Code
+		}
+
+		if status >= config.minStatus {
+			req := c.Request()
+
+			cause := err
+			if internal := internalError(err); internal != nil {
+				cause = internal
+			}
+
+			fields := []zapcore.Field{
+				zap.Error(err),
+				zap.Int("status", status),
+				zap.String("request", fmt.Sprintf("%s %s", req.Method, req.RequestURI)),
+				zap.String("request_id", requestID(c)),
+				zap.Strings("error_chain", errorChain(cause)),
+			}
+			if stack := stackTrace(cause); stack != "" {
+				fields = append(fields, zap.String("error_stack", stack))
+			}
+
+			if status >= http.StatusInternalServerError {
+				logger.Error("Request error", fields...)
+			} else {
+				logger.Warn("Request error", fields...)
+			}
+		}
+
+		if config.next != nil {
+			config.next(err, c)
+			return
+		}
+		c.Echo().DefaultHTTPErrorHandler(err, c)
+	}
+}
+
+// causer is implemented by github.com/pkg/errors errors wrapping another one
+type causer interface {
+	Cause() error
+}
+
+// stackTrace returns the stack trace of the innermost error of the chain with a StackTrace method
+// returning a value formatted with %+v, such as the errors of github.com/pkg/errors, or ""
+func stackTrace(err error) string {
+	var stack string
+	for i := 0; err != nil && i < maxErrorChainDepth; i++ {
+		method := reflect.ValueOf(err).MethodByName("StackTrace")
+		if method.IsValid() && method.Type().NumIn() == 0 && method.Type().NumOut() == 1 {
+			stack = strings.TrimSpace(fmt.Sprintf("%+v", method.Call(nil)[0].Interface()))
+		}
+
+		switch e := err.(type) {
+		case wrapper:
+			err = e.Unwrap()
+		case causer:
+			err = e.Cause()
+		default:
+			err = nil
+		}
+	}
+	return stack
+}
@@ -0,0 +1,101 @@
+package echozap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// frames stands for github.com/pkg/errors.StackTrace, printing frames with %+v
+type frames []string
+
+func (f frames) Format(s fmt.State, verb rune) {
+	for _, frame := range f {
+		fmt.Fprintf(s, "\n%s", frame)
+	}
+}
+
+// stackError is an error carrying a stack trace like those of github.com/pkg/errors
+type stackError struct {
+	msg   string
+	stack frames
+}
+
+func (e *stackError) Error() string      { return e.msg }
+func (e *stackError) StackTrace() frames { return e.stack }
+
+func TestNewZapHTTPErrorHandler(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	cause := &stackError{msg: "connection refused", stack: frames{"main.query", "main.loadUser"}}
+
+	e := echo.New()
+	e.HTTPErrorHandler = NewZapHTTPErrorHandler(zap.New(obs))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(&wrappedError{msg: "load user", cause: cause})
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return echo.ErrNotFound
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, 1, logs.Len())
+
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	assert.Equal(t, "Request error", entry.Message)
+
+	logFields := entry.ContextMap()
+	assert.Equal(t, "GET /users/42", logFields["request"])
+	assert.Equal(t, []interface{}{"load user: connection refused", "connection refused"}, logFields["error_chain"])
+	assert.Equal(t, "main.query\nmain.loadUser", logFields["error_stack"])
+}
+
+func TestNewZapHTTPErrorHandlerOptions(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	var delegated error
+
+	e := echo.New()
+	e.HTTPErrorHandler = NewZapHTTPErrorHandler(zap.New(obs),
+		WithMinStatus(http.StatusBadRequest),
+		WithNextHandler(func(err error, c echo.Context) {
+			delegated = err
+			c.NoContent(http.StatusTeapot)
+		}),
+	)
+	e.GET("/", func(c echo.Context) error {
+		return errors.New("plain")
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return echo.ErrNotFound
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, echo.ErrNotFound, delegated)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, zapcore.WarnLevel, logs.All()[0].Level)
+	assert.Equal(t, zapcore.ErrorLevel, logs.All()[1].Level)
+	assert.Equal(t, []interface{}{"plain"}, logs.All()[1].ContextMap()["error_chain"])
+	assert.NotContains(t, logs.All()[1].ContextMap(), "error_stack")
+}
@@ -0,0 +1,73 @@
+package echozap
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxErrorChainDepth bounds the number of errors walked by errorChain
+const maxErrorChainDepth = 16
+
+// wrapper is implemented by errors wrapping another one (the errors.Unwrap convention)
+type wrapper interface {
+	Unwrap() error
+}
+
+// errorChain returns the messages of err and of every error it wraps, outermost first
+func errorChain(err error) []string {
+	var chain []string
+	for err != nil && len(chain) < maxErrorChainDepth {
+		chain = append(chain, err.Error())
+
+		w, ok := err.(wrapper)
+		if !ok {
+			break
+		}
+		err = w.Unwrap()
+	}
+	return chain
+}
+
+// internalError returns the Internal error of an *echo.HTTPError, or nil
+func internalError(err error) error {
+	if he, ok := err.(*echo.HTTPError); ok {
+		return he.Internal
+	}
+	return nil
+}
+
+// PanicInfo describes a panic recovered by another middleware. Recover middleware may store it, or
+// the recovered value itself, under Options.PanicInfoKey.
+type PanicInfo struct {
+	// Value is the value passed to panic
+	Value interface{}
+	// Stack is the stack trace of the panic, optional
+	Stack []byte
+}
+
+// panicInfo returns the panic stored in a context value, accepting a PanicInfo, a *PanicInfo or the
+// recovered value itself
+func panicInfo(value interface{}) (PanicInfo, bool) {
+	switch info := value.(type) {
+	case nil:
+		return PanicInfo{}, false
+	case PanicInfo:
+		return info, true
+	case *PanicInfo:
+		if info == nil {
+			return PanicInfo{}, false
+		}
+		return *info, true
+	default:
+		return PanicInfo{Value: info}, true
+	}
+}
+
+// panicMessage formats a recovered panic value
+func panicMessage(value interface{}) string {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,106 @@
+package echozap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// wrappedError is a minimal error wrapping another one
+type wrappedError struct {
+	msg   string
+	cause error
+}
+
+func (e *wrappedError) Error() string { return e.msg + ": " + e.cause.Error() }
+func (e *wrappedError) Unwrap() error { return e.cause }
+
+func TestErrorChain(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := &wrappedError{msg: "load user", cause: &wrappedError{msg: "query", cause: cause}}
+
+	assert.Equal(t, []string{
+		"load user: query: connection refused",
+		"query: connection refused",
+		"connection refused",
+	}, errorChain(err))
+	assert.Nil(t, errorChain(nil))
+}
+
+func TestErrorChainDepth(t *testing.T) {
+	var err error = errors.New("root")
+	for i := 0; i < 2*maxErrorChainDepth; i++ {
+		err = &wrappedError{msg: "layer", cause: err}
+	}
+
+	assert.Len(t, errorChain(err), maxErrorChainDepth)
+}
+
+func TestInternalError(t *testing.T) {
+	cause := errors.New("duplicate key")
+
+	assert.Equal(t, cause, internalError(echo.NewHTTPError(http.StatusConflict).SetInternal(cause)))
+	assert.Nil(t, internalError(echo.NewHTTPError(http.StatusConflict)))
+	assert.Nil(t, internalError(cause))
+}
+
+func TestZapLoggerPanicInfo(t *testing.T) {
+	values := []interface{}{
+		PanicInfo{Value: "nil map", Stack: []byte("goroutine 1 [running]:")},
+		&PanicInfo{Value: errors.New("nil map"), Stack: []byte("goroutine 1 [running]:")},
+		"nil map",
+	}
+
+	for _, value := range values {
+		obs, logs := observer.New(zap.DebugLevel)
+
+		e := echo.New()
+		e.Use(ZapLogger(&Options{Logger: zap.New(obs), PanicInfoKey: "panic"}))
+		// stands for a recover middleware storing what it caught
+		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						c.Set("panic", value)
+						err = echo.NewHTTPError(http.StatusInternalServerError)
+					}
+				}()
+				return next(c)
+			}
+		})
+		e.GET("/", func(c echo.Context) error {
+			panic("nil map")
+		})
+
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		logFields := logs.All()[0].ContextMap()
+		assert.Equal(t, true, logFields["panic_recovered"])
+		assert.Equal(t, "nil map", logFields["panic_message"])
+		if _, ok := value.(string); ok {
+			assert.NotContains(t, logFields, "panic_stack")
+		} else {
+			assert.Equal(t, "goroutine 1 [running]:", logFields["panic_stack"])
+		}
+	}
+}
+
+func TestZapLoggerWithoutPanicInfo(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), PanicInfoKey: "panic"}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.NotContains(t, logs.All()[0].ContextMap(), "panic_recovered")
+}
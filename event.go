@@ -0,0 +1,80 @@
+package echozap
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LogEvent describes a logged request. It is sent to Options.EventSink.
+type LogEvent struct {
+	// Time is when the request was received
+	Time time.Time
+	// Latency is the time spent handling the request
+	Latency time.Duration
+	// Status is the logged response status
+	Status int
+	// Method is the request method
+	Method string
+	// Path is the request URL path
+	Path string
+	// Route is the matched route template, empty when no route matched
+	Route string
+	// Host is the request host
+	Host string
+	// RemoteIP is the client IP, anonymized with Options.AnonymizeIP
+	RemoteIP string
+	// Size is the response size in bytes
+	Size int64
+	// RequestID is the logged request id
+	RequestID string
+	// Error is the error returned by the handler, if any
+	Error error
+}
+
+// MetricsRecorder records metrics for the requests handled by the middleware, such as request counts and
+// latency histograms labelled by method, route and status class. Set as Options.Metrics, it is called
+// for every request, sampled out or not, and concurrently.
+type MetricsRecorder interface {
+	RecordRequest(event LogEvent)
+}
+
+// MetricsRecorderFunc adapts a function to MetricsRecorder.
+type MetricsRecorderFunc func(event LogEvent)
+
+// RecordRequest calls f.
+func (f MetricsRecorderFunc) RecordRequest(event LogEvent) {
+	f(event)
+}
+
+// sendEvent sends event to sink. Unless block is set, the event is dropped when sink is full.
+func sendEvent(sink chan<- LogEvent, block bool, event LogEvent) {
+	if block {
+		sink <- event
+		return
+	}
+
+	select {
+	case sink <- event:
+	default:
+	}
+}
+
+// newLogEvent builds the event for the request handled by c
+func newLogEvent(options *Options, c echo.Context, start time.Time, latency time.Duration, status int, id string, err error) LogEvent {
+	req := c.Request()
+
+	return LogEvent{
+		Time:      start,
+		Latency:   latency,
+		Status:    status,
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Route:     matchedRoute(c),
+		Host:      req.Host,
+		RemoteIP:  remoteIP(options, c),
+		Size:      c.Response().Size,
+		RequestID: id,
+		Error:     err,
+	}
+}
@@ -0,0 +1,108 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerEventSink(t *testing.T) {
+	events := make(chan LogEvent, 1)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.NewNop(), EventSink: events}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusCreated, "created")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	event := <-events
+	assert.Equal(t, http.StatusCreated, event.Status)
+	assert.Equal(t, http.MethodGet, event.Method)
+	assert.Equal(t, "/users/42", event.Path)
+	assert.Equal(t, "/users/:id", event.Route)
+	assert.Equal(t, int64(7), event.Size)
+	assert.Equal(t, "abc", event.RequestID)
+	assert.False(t, event.Time.IsZero())
+	assert.True(t, event.Latency >= 0)
+	assert.Nil(t, event.Error)
+}
+
+func TestZapLoggerEventSinkFull(t *testing.T) {
+	events := make(chan LogEvent)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.NewNop(), EventSink: events}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, events, 0)
+}
+
+func TestZapLoggerMetrics(t *testing.T) {
+	var events []LogEvent
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:  zap.NewNop(),
+		Sampler: func(echo.Context) bool { return false },
+		Metrics: MetricsRecorderFunc(func(event LogEvent) {
+			events = append(events, event)
+		}),
+	}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusCreated, "created")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, http.MethodGet, events[0].Method)
+	assert.Equal(t, "/users/:id", events[0].Route)
+	assert.Equal(t, http.StatusCreated, events[0].Status)
+	assert.Equal(t, int64(7), events[0].Size)
+	assert.Equal(t, http.StatusNotFound, events[1].Status)
+}
+
+func TestZapLoggerMetricsDisabledEntry(t *testing.T) {
+	obs, logs := observer.New(zap.ErrorLevel)
+
+	var events []LogEvent
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Metrics: MetricsRecorderFunc(func(event LogEvent) {
+			events = append(events, event)
+		}),
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/escalated", func(c echo.Context) error {
+		SetLevel(c, zapcore.ErrorLevel)
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/escalated", nil))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "GET /escalated", logs.All()[0].ContextMap()["request"])
+}
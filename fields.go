@@ -0,0 +1,55 @@
+package echozap
+
+import (
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StandardFields returns the "service", "version" and "env" fields identifying the running
+// application, skipping empty values. Use them as Options.StaticFields and with zap.Fields on the
+// application logger so access logs and handler logs share the same correlation fields.
+func StandardFields(service, version, env string) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, 3)
+	if service != "" {
+		fields = append(fields, zap.String("service", service))
+	}
+	if version != "" {
+		fields = append(fields, zap.String("version", version))
+	}
+	if env != "" {
+		fields = append(fields, zap.String("env", env))
+	}
+	return fields
+}
+
+// EnvFields returns a field for each of the named environment variables, such as values injected by
+// deploy tooling, keyed by the lowercased name (REGION becomes "region"). Variables are read when it is
+// called, unset or empty ones are skipped. Use them as Options.StaticFields:
+//
+//	Options{Logger: logger, StaticFields: echozap.EnvFields("REGION", "POD_NAME")}
+func EnvFields(keys ...string) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			fields = append(fields, zap.String(strings.ToLower(key), value))
+		}
+	}
+	return fields
+}
+
+// appendTopLevel appends extra to fields at the top level of the entry, before the first namespace which
+// would nest them. fields is copied when extra has to be inserted.
+func appendTopLevel(fields []zapcore.Field, extra ...zapcore.Field) []zapcore.Field {
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			out := make([]zapcore.Field, 0, len(fields)+len(extra))
+			out = append(out, fields[:i]...)
+			out = append(out, extra...)
+			return append(out, fields[i:]...)
+		}
+	}
+	return append(fields, extra...)
+}
@@ -0,0 +1,113 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestStandardFields(t *testing.T) {
+	assert.Equal(t, []zapcore.Field{
+		zap.String("service", "api"),
+		zap.String("version", "1.2.0"),
+		zap.String("env", "prod"),
+	}, StandardFields("api", "1.2.0", "prod"))
+
+	assert.Equal(t, []zapcore.Field{zap.String("service", "api")}, StandardFields("api", "", ""))
+}
+
+func TestZapLoggerStaticFields(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), StaticFields: StandardFields("api", "1.2.0", "prod")})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "api", logFields["service"])
+	assert.Equal(t, "1.2.0", logFields["version"])
+	assert.Equal(t, "prod", logFields["env"])
+}
+
+func TestZapLoggerEnvFields(t *testing.T) {
+	os.Setenv("ECHOZAP_TEST_REGION", "eu-west-1")
+	defer os.Unsetenv("ECHOZAP_TEST_REGION")
+	os.Setenv("ECHOZAP_TEST_POD_NAME", "api-7d4f")
+	defer os.Unsetenv("ECHOZAP_TEST_POD_NAME")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	fields := EnvFields("ECHOZAP_TEST_REGION", "ECHOZAP_TEST_POD_NAME", "ECHOZAP_TEST_UNSET")
+	err := ZapLogger(&Options{Logger: zap.New(obs), StaticFields: fields})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "eu-west-1", logFields["echozap_test_region"])
+	assert.Equal(t, "api-7d4f", logFields["echozap_test_pod_name"])
+	assert.NotContains(t, logFields, "echozap_test_unset")
+}
+
+func TestZapLoggerFieldPrefix(t *testing.T) {
+	for _, prefixCustom := range []bool{false, true} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		custom := []zapcore.Field{zap.String("tenant", "acme")}
+		h := func(c echo.Context) error {
+			c.Set(DefaultCustomFieldsKey, custom)
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:             zap.New(obs),
+			FieldPrefix:        "access_",
+			PrefixCustomFields: prefixCustom,
+			LogStatusClass:     true,
+		})(h)(c)
+
+		assert.Nil(t, err)
+
+		logFields := logs.All()[0].ContextMap()
+		for _, key := range []string{"status", "latency", "remote_ip", "request", "size", "user_agent", "status_class", "request_id"} {
+			assert.Contains(t, logFields, "access_"+key)
+			assert.NotContains(t, logFields, key)
+		}
+
+		if prefixCustom {
+			assert.Equal(t, "acme", logFields["access_tenant"])
+		} else {
+			assert.Equal(t, "acme", logFields["tenant"])
+		}
+		assert.Equal(t, "tenant", custom[0].Key)
+	}
+}
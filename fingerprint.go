@@ -0,0 +1,49 @@
+package echozap
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// FingerprintComponent returns one component of a request fingerprint.
+type FingerprintComponent func(c echo.Context) string
+
+// FingerprintIP uses the real client IP.
+func FingerprintIP(c echo.Context) string {
+	return c.RealIP()
+}
+
+// FingerprintPath uses the request path.
+func FingerprintPath(c echo.Context) string {
+	return c.Request().URL.Path
+}
+
+// FingerprintUserAgent uses the User-Agent header.
+func FingerprintUserAgent(c echo.Context) string {
+	return c.Request().UserAgent()
+}
+
+// FingerprintHeader uses the value of a request header.
+func FingerprintHeader(name string) FingerprintComponent {
+	return func(c echo.Context) string {
+		return c.Request().Header.Get(name)
+	}
+}
+
+// DefaultFingerprintComponents returns the components used when Options.FingerprintComponents is empty:
+// the client IP, the path and the User-Agent.
+func DefaultFingerprintComponents() []FingerprintComponent {
+	return []FingerprintComponent{FingerprintIP, FingerprintPath, FingerprintUserAgent}
+}
+
+// Fingerprint returns the SHA-256 based hash of the components of a request. A rate limiter calling it
+// with the same components keys on the value logged as "fingerprint".
+func Fingerprint(c echo.Context, components ...FingerprintComponent) string {
+	values := make([]string, len(components))
+	for i, component := range components {
+		values[i] = component(c)
+	}
+	// the separator can't appear in header values, so components can't shift into each other
+	return hashString(HashSHA256, strings.Join(values, "\x00"))
+}
@@ -0,0 +1,51 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(ip, path, ua string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderXRealIP, ip)
+		req.Header.Set("User-Agent", ua)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		return Fingerprint(c, DefaultFingerprintComponents()...)
+	}
+
+	base := fingerprint("203.0.113.7", "/login", "curl/7.64.1")
+	assert.Len(t, base, 32)
+	assert.Equal(t, base, fingerprint("203.0.113.7", "/login", "curl/7.64.1"))
+
+	assert.NotEqual(t, base, fingerprint("203.0.113.8", "/login", "curl/7.64.1"))
+	assert.NotEqual(t, base, fingerprint("203.0.113.7", "/logout", "curl/7.64.1"))
+	assert.NotEqual(t, base, fingerprint("203.0.113.7", "/login", "curl/7.65.0"))
+}
+
+func TestZapLoggerFingerprint(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.Header.Set("X-Api-Key", "key-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	components := []FingerprintComponent{FingerprintHeader("X-Api-Key"), FingerprintPath}
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogFingerprint: true, FingerprintComponents: components})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Fingerprint(c, components...), logs.All()[0].ContextMap()["fingerprint"])
+	assert.NotEqual(t, Fingerprint(c, DefaultFingerprintComponents()...), logs.All()[0].ContextMap()["fingerprint"])
+}
@@ -4,6 +4,7 @@ go 1.12
 
 require (
 	github.com/labstack/echo/v4 v4.1.10
+	github.com/labstack/gommon v0.3.0
 	github.com/pkg/errors v0.8.1 // indirect
 	github.com/stretchr/testify v1.4.0
 	go.uber.org/atomic v1.4.0 // indirect
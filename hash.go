@@ -0,0 +1,29 @@
+package echozap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+)
+
+// HashAlgorithm selects how values are hashed before being logged.
+type HashAlgorithm string
+
+const (
+	// HashFNV hashes with 64-bit FNV-1a. It is fast but not meant to resist brute forcing.
+	HashFNV HashAlgorithm = "fnv"
+	// HashSHA256 hashes with SHA-256, truncated to 16 bytes.
+	HashSHA256 HashAlgorithm = "sha256"
+)
+
+// hashString returns the hex encoded hash of s, using FNV-1a unless alg says otherwise
+func hashString(alg HashAlgorithm, s string) string {
+	if alg == HashSHA256 {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:16])
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,18 @@
+package echozap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashString(t *testing.T) {
+	for _, alg := range []HashAlgorithm{HashFNV, HashSHA256} {
+		assert.Equal(t, hashString(alg, "/users/42"), hashString(alg, "/users/42"), alg)
+		assert.NotEqual(t, hashString(alg, "/users/42"), hashString(alg, "/users/43"), alg)
+	}
+
+	assert.Len(t, hashString(HashFNV, "/users/42"), 16)
+	assert.Len(t, hashString(HashSHA256, "/users/42"), 32)
+	assert.Equal(t, hashString(HashFNV, "/"), hashString("", "/"))
+}
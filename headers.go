@@ -0,0 +1,44 @@
+package echozap
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+)
+
+// sensitiveHeaders are the headers whose values are never logged, wherever headers are
+var sensitiveHeaders = []string{echo.HeaderAuthorization, "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// selectedHeadersObject marshals the selected headers as a zap object keyed by their canonical name,
+// multiple values being joined with ", "
+type selectedHeadersObject struct {
+	header   http.Header
+	names    []string
+	redactor func(name, value string) string
+	// anonymize anonymizes the client IP headers
+	anonymize bool
+}
+
+func (h selectedHeadersObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, name := range h.names {
+		name = http.CanonicalHeaderKey(name)
+		values, ok := h.header[name]
+		if !ok {
+			continue
+		}
+
+		value := strings.Join(values, ", ")
+		switch {
+		case containsString(sensitiveHeaders, name):
+			value = redactedValue
+		case h.anonymize && containsString(clientIPHeaders, name):
+			value = anonymizeIPHeader(value)
+		case h.redactor != nil:
+			value = h.redactor(name, value)
+		}
+		enc.AddString(name, value)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerSelectedHeaders(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.Header.Set(echo.HeaderAccept, "application/json")
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	req.Header.Set("X-Api-Token", "token")
+	req.Header.Add("Cookie", "session=1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "max-age=60")
+		c.Response().Header().Add("Vary", echo.HeaderAccept)
+		c.Response().Header().Add("Vary", "Accept-Encoding")
+		c.Response().Header().Set("Set-Cookie", "session=2")
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:             zap.New(obs),
+		LogRequestHeaders:  []string{"accept", "Authorization", "Cookie", "X-Api-Token", "X-Missing"},
+		LogResponseHeaders: []string{"Cache-Control", "Vary", "Set-Cookie"},
+		HeaderRedactor: func(name, value string) string {
+			if name == "X-Api-Token" {
+				return strings.Repeat("*", len(value))
+			}
+			return value
+		},
+	})(h)(c)
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, map[string]interface{}{
+		"Accept":        "application/json",
+		"Authorization": redactedValue,
+		"Cookie":        redactedValue,
+		"X-Api-Token":   "*****",
+	}, logFields["request_headers"])
+	assert.Equal(t, map[string]interface{}{
+		"Cache-Control": "max-age=60",
+		"Vary":          "Accept, Accept-Encoding",
+		"Set-Cookie":    redactedValue,
+	}, logFields["response_headers"])
+}
+
+func TestZapLoggerSelectedHeadersDisabled(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+	assert.Nil(t, err)
+
+	assert.NotContains(t, logs.All()[0].ContextMap(), "request_headers")
+	assert.NotContains(t, logs.All()[0].ContextMap(), "response_headers")
+}
@@ -0,0 +1,51 @@
+package echozap
+
+import "go.uber.org/zap/zapcore"
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// Contains reports whether status is within the range.
+func (r StatusRange) Contains(status int) bool {
+	return status >= r.Min && status <= r.Max
+}
+
+// LevelRule logs the statuses in Range at Level.
+type LevelRule struct {
+	Range StatusRange
+	Level zapcore.Level
+}
+
+// defaultLevel returns the level used for a status when no rule matches. A zero status means
+// the handler never wrote a response and is logged as a warning.
+func defaultLevel(status int) zapcore.Level {
+	switch {
+	case status >= 500:
+		return zapcore.ErrorLevel
+	case status >= 400, status == 0:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// statusLevel returns the level of the first rule matching status, falling back to the default
+func statusLevel(rules []LevelRule, status int) zapcore.Level {
+	if level, ok := ruleLevel(rules, status); ok {
+		return level
+	}
+	return defaultLevel(status)
+}
+
+// ruleLevel returns the level of the first rule matching status
+func ruleLevel(rules []LevelRule, status int) (zapcore.Level, bool) {
+	for _, rule := range rules {
+		if rule.Range.Contains(status) {
+			return rule.Level, true
+		}
+	}
+	return zapcore.InfoLevel, false
+}
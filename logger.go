@@ -1,8 +1,17 @@
 package echozap
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -15,85 +24,1636 @@ const (
 	DefaultCustomFieldsKey = "_echozap_custom_fields_"
 	// DefaultCustomLoggerKey is the key for custom logger in the context.
 	DefaultCustomLoggerKey = "_echozap_custom_logger_"
+	// DefaultEntryKey is the key of the object produced by Options.EntryMarshaler or Options.OrderedFields.
+	DefaultEntryKey = "http"
 )
 
 type Options struct {
-	// Logger is the zap logger to use
+	// Logger is the zap logger to use. The field slices of entries are reused once written, so its core must
+	// copy rather than retain them, as the zap cores do.
 	Logger *zap.Logger
-	// CustomFieldsKey is the key to use for custom fields (default: echozap.DefaultCustomFieldsPrefix)
+	// CustomFieldsKey is the key to use for custom fields (default: echozap.DefaultCustomFieldsKey, see SetDefaultKeys)
 	CustomFieldsKey string
-	// CustomLoggerKey is the key to use for the custom logger (default: echozap.DefaultCustomLoggerKey)
+	// CustomLoggerKey is the key to use for the custom logger (default: echozap.DefaultCustomLoggerKey, see SetDefaultKeys)
 	CustomLoggerKey string
+	// LevelRules overrides the level for status ranges. Rules are evaluated in order and the first
+	// match wins; statuses matching no rule use the default levels (5xx Error, 4xx Warn, else Info).
+	LevelRules []LevelRule
+	// LevelResolver returns the level of the entry from the request and the handler error, replacing the
+	// status based level and LevelRules. SetLevel still takes precedence.
+	LevelResolver func(c echo.Context, err error) zapcore.Level
+	// LogOperation adds an "operation" field made of the method and the matched route (e.g. "GET /users/:id")
+	LogOperation bool
+	// LogStatusText adds a "status_text" field with the standard text of the status (e.g. "Not Found")
+	LogStatusText bool
+	// NormalizeUnknownStatus logs responses that never set a status as 200 with a "status_unknown" field.
+	// By default they are logged at Warn with an "Unknown status" message.
+	NormalizeUnknownStatus bool
+	// EntryMarshaler replaces the access-log fields with a single object built from the request context.
+	// Custom fields and the request id are still appended next to it.
+	EntryMarshaler func(c echo.Context) zapcore.ObjectMarshaler
+	// EntryKey is the key of the object produced by EntryMarshaler or OrderedFields (default: echozap.DefaultEntryKey)
+	EntryKey string
+	// ErrorSampleFirst enables sampling of Error level entries logged with Logger: each second only the
+	// first ErrorSampleFirst entries with the same message are logged, then every ErrorSampleThereafter-th.
+	// Loggers taken from the context or returned by LoggerResolver are not sampled.
+	ErrorSampleFirst int
+	// ErrorSampleThereafter is the sampling rate once ErrorSampleFirst is reached (0 drops them all)
+	ErrorSampleThereafter int
+	// LogEffectiveHost adds an "effective_host" field with the host the request was served for
+	// (on HTTP/2 it comes from the :authority pseudo-header)
+	LogEffectiveHost bool
+	// LogRawHostHeader adds a "raw_host_header" field with the Host header as sent, when it can be
+	// recovered: net/http removes it from the request headers, so on HTTP/1 it is the request host unless
+	// the request-URI was in absolute form, and on HTTP/2 the Host header sent besides :authority
+	LogRawHostHeader bool
+	// LogCompressed adds a "compressed" field telling whether the response was gzip, br or deflate encoded
+	LogCompressed bool
+	// EventSink receives a LogEvent for every logged request, in addition to the log entry.
+	// Events are dropped when the channel is full unless EventSinkBlocking is set.
+	EventSink chan<- LogEvent
+	// EventSinkBlocking makes the middleware wait for room in EventSink instead of dropping events
+	EventSinkBlocking bool
+	// LogRemoteAddr adds a "remote_addr" field with the IP of the connection, ignoring proxy headers
+	LogRemoteAddr bool
+	// EmitServerTiming adds a "Server-Timing: total;dur=<ms>" response header. Headers can't change once
+	// the response is committed, so the duration covers the handler up to the moment it wrote the status,
+	// or the whole handler when it left the response uncommitted.
+	EmitServerTiming bool
+	// CustomFieldsNamespace nests the custom fields under this key instead of the top level of the entry
+	CustomFieldsNamespace string
+	// LogStatusClass adds a "status_class" field with the first digit of the status (e.g. 4 for a 404)
+	LogStatusClass bool
+	// LogTimestamps adds "received_at" and "completed_at" RFC3339Nano fields delimiting the request
+	LogTimestamps bool
+	// StaticFields are added to every entry (see StandardFields)
+	StaticFields []zapcore.Field
+	// LogErrorHandled adds an "error_handled_by_middleware" field telling whether the handler returned an
+	// error that the middleware passed to the echo error handler, rather than writing its own response
+	LogErrorHandled bool
+	// LogLatencyBudget adds a "latency_budget_pct" field when the request context has a deadline: the
+	// latency as a percentage of the time the request had between its start and the deadline, capped at 100
+	LogLatencyBudget bool
+	// LogRequestKind adds a "request_kind" field classifying the request Content-Type as json, form,
+	// multipart or other
+	LogRequestKind bool
+	// LogErrorChain adds an "error_chain" field with the message of the handler error and of every
+	// error it wraps (through an Unwrap method), outermost first
+	LogErrorChain bool
+	// LogStatusSource adds a "status_source" field with the component recorded by MarkSource
+	LogStatusSource bool
+	// LogAuthScheme adds an "auth_scheme" field with the scheme of the Authorization header (e.g. "Bearer",
+	// or "none"). The credentials are never logged.
+	LogAuthScheme bool
+	// LogPathHash adds a "path_hash" field with a hash of the URL path, for bucketing paths without
+	// storing them. The path is replaced by its hash in the other fields logging it, such as "request";
+	// path parameters logged with LogPathParams are not affected.
+	LogPathHash bool
+	// PathHashAlgorithm is the algorithm used for "path_hash" (default: echozap.HashFNV)
+	PathHashAlgorithm HashAlgorithm
+	// LogBodyReadTime adds a "body_read_ms" field with the time the handler spent reading the request
+	// body, which includes waiting on slow clients
+	LogBodyReadTime bool
+	// LogInternalError adds an "internal_error" field with the Internal error of an *echo.HTTPError
+	// returned by the handler, which often holds the cause behind a generic message
+	LogInternalError bool
+	// LogFieldCount adds a "field_count" field with the number of fields of the entry, custom ones
+	// included, not counting itself and the error
+	LogFieldCount bool
+	// LogWriteTiming adds "ttfb_ms" (time to the first body write) and "write_duration_ms" (time between
+	// the first and the end of the last write) fields to responses with a body
+	LogWriteTiming bool
+	// LogBaggageKeys lists the W3C baggage header entries added under a "baggage" object
+	LogBaggageKeys []string
+	// LogConditional adds an "if_none_match" field telling whether the request was conditional
+	// (If-None-Match or If-Modified-Since header), to correlate with 304 responses
+	LogConditional bool
+	// LogGRPCStatus adds a "grpc_status" field with the Grpc-Status response header set by grpc-gateway
+	LogGRPCStatus bool
+	// DetectDeadLinks logs 404s referred by a page of the same host with a "dead_link" field at Warn, and
+	// other 404s at Info. LevelRules matching 404 take precedence.
+	DetectDeadLinks bool
+	// InjectScopedLogger stores under CustomLoggerKey, before calling the handler, a logger carrying the
+	// "request_id", "method", "route" and "remote_ip" of the request so that handler logs are correlated
+	// with the access log; see FromContext. The access log is written with that logger too, less remote_ip
+	// which is a standard field.
+	InjectScopedLogger bool
+	// RedirectCountHeader names a request header (e.g. "X-Redirect-Count") holding the number of internal
+	// redirects that led to the request, logged as "redirect_count" when it is a valid integer
+	RedirectCountHeader string
+	// LogLocale adds a "locale" field with the preferred language of the Accept-Language header
+	LogLocale bool
+	// FailClosed propagates panics raised while writing the entry (e.g. by a broken encoder). By default
+	// they are recovered, reported to FallbackLogger and the request completes normally.
+	FailClosed bool
+	// FallbackLogger reports recovered logging panics (default: a JSON logger writing to stderr)
+	FallbackLogger *zap.Logger
+	// URLObject adds a "url" object with the "scheme", "host", "path" and "query" of the request
+	URLObject bool
+	// SensitiveParams lists query parameters whose values are replaced by "[REDACTED]" in the "request"
+	// field (or the URI field of the Preset), the "url" object and the "query" object
+	SensitiveParams []string
+	// LoggerResolver selects the logger of a request once it has been handled (e.g. per tenant). When it
+	// returns nil, the logger from the context or Logger is used. It is called concurrently.
+	LoggerResolver func(c echo.Context) *zap.Logger
+	// LogMutation adds an "is_mutation" field telling whether the method changes state (POST, PUT, PATCH, DELETE)
+	LogMutation bool
+	// LogCorrelationID adds a "correlation_id" field with the id of the first of CorrelationSources that
+	// has one. The id is also written to the CorrelationIDHeader response header and, with
+	// InjectScopedLogger, to the scoped logger.
+	LogCorrelationID bool
+	// CorrelationSources are tried in order to find the correlation id (default: DefaultCorrelationSources())
+	CorrelationSources []CorrelationSource
+	// CorrelationIDHeader is the response header the correlation id is written to (default: echozap.DefaultCorrelationIDHeader)
+	CorrelationIDHeader string
+	// LogRouteGroup adds a "route_group" field with the group prefix recorded by the RouteGroup middleware
+	LogRouteGroup bool
+	// LogFlushCount adds a "flush_count" field with the number of times the response was flushed, i.e.
+	// the chunks sent by streaming handlers
+	LogFlushCount bool
+	// RetryAttemptHeader names a request header (e.g. "X-Retry-Attempt") holding the client retry attempt,
+	// logged as "retry_attempt" when it is a positive integer
+	RetryAttemptHeader string
+	// OmitLatencyBelow drops the latency fields of requests faster than this duration, to save log volume
+	// on trivially fast responses: "latency" (or the latency of the Preset or Values) and "latency_ms"
+	OmitLatencyBelow time.Duration
+	// Preset selects the names of the standard fields, such as the ECS or Google Cloud Logging schemas
+	// (default: echozap.PresetDefault)
+	Preset Preset
+	// DebugBodyHeader names a response header (e.g. "X-Debug-Log-Body") handlers set to "true" to have the
+	// request and response bodies logged as "request_body" and "response_body". Bodies are always buffered
+	// up to DebugBodyLimit but only logged when the header is set; it is removed before the response is sent.
+	DebugBodyHeader string
+	// DebugBodyLimit is the number of bytes of each body kept for DebugBodyHeader (default: echozap.DefaultMaxBodySize)
+	DebugBodyLimit int
+	// Sampler decides which entries are kept. When set, entries carry a "sampled" field with the decision
+	// and the rejected ones are dropped, or logged at Debug with LogSampledOut.
+	Sampler Sampler
+	// LogSampledOut logs the entries rejected by Sampler at Debug instead of dropping them
+	LogSampledOut bool
+	// LogDeviceType adds a "device_type" field guessed from the User-Agent by DeviceClassifier
+	LogDeviceType bool
+	// DeviceClassifier returns the device type of a User-Agent, "" to omit the field (default: echozap.ClassifyDevice)
+	DeviceClassifier func(userAgent string) string
+	// SlowRequestThreshold is the latency above which a request is considered slow, zero disables slow
+	// detection. Entries of slow requests carry "slow_request" and "slow_threshold" fields and are logged at
+	// Warn or above.
+	SlowRequestThreshold time.Duration
+	// LogSlowDiagnostics adds a "num_goroutines" field to entries of slow requests
+	LogSlowDiagnostics bool
+	// CoalesceWindow collapses identical entries (same method, route and status) seen within the window
+	// into a single entry with a "count" field, written once the window closes. Zero disables coalescing.
+	CoalesceWindow time.Duration
+	// ClockSkewHeader names a request header holding the time the request was sent or received upstream,
+	// such as Date or X-Request-Start, and adds a "clock_skew_ms" field with its signed difference from the
+	// server receive time, positive when the header is ahead. Unparsable headers are ignored.
+	ClockSkewHeader string
+	// ValidationErrorsKey is the context key a validation middleware stores ValidationErrors under,
+	// logged as a "validation_errors" array when present
+	ValidationErrorsKey string
+	// LogTimeout adds a "timeout_ms" field with the request context deadline relative to the start of the
+	// request, whether or not it was exceeded. Requests without a deadline are not affected.
+	LogTimeout bool
+	// CompactLatency formats the "latency" field rounded to its most significant unit (e.g. 1.2ms) instead
+	// of the full duration, which reads better with console encoders
+	CompactLatency bool
+	// LogCacheControl adds a "cache_control" field with the response Cache-Control header, when set
+	LogCacheControl bool
+	// DebugQueryParam names a query parameter which, when true (e.g. ?__debug=1), adds the request headers,
+	// size, path parameters and query to the entry. Empty disables verbose entries.
+	DebugQueryParam string
+	// LogUnreadBody adds an "unread_body_bytes" field with the part of the request body, as declared by
+	// Content-Length, the handler didn't read. Requests without a Content-Length are not affected.
+	LogUnreadBody bool
+	// LowPriorityPaths are request paths, such as health checks, whose successful requests are only logged
+	// when LowPrioritySampler keeps them. Failed requests (status 400 and above) are always logged.
+	LowPriorityPaths []string
+	// LowPrioritySampler decides which successful requests to LowPriorityPaths are logged, none when nil
+	LowPrioritySampler Sampler
+	// InjectContextLogger stores the request logger, scoped when InjectScopedLogger is set, in the request
+	// context.Context for code that receives it instead of the echo.Context; see LoggerFromContext
+	InjectContextLogger bool
+	// LogConnReused adds a "conn_reused" field telling whether the request arrived on a connection that
+	// already served requests, as set with WithConnReused or tracked by ConnTracker. Requests where it is
+	// unknown are not affected.
+	LogConnReused bool
+	// ConnTracker tracks connection reuse for LogConnReused, when it isn't set in the request context
+	ConnTracker *ConnTracker
+	// AuditLogger receives the audit entries recorded with SetAudit (default: Logger)
+	AuditLogger *zap.Logger
+	// MaxHeaderCount flags requests with more header values than this with a "large_headers" field, and
+	// logs them at least at warn level. Zero disables the check.
+	MaxHeaderCount int
+	// MaxHeaderBytes flags requests whose header names and values total more bytes than this like
+	// MaxHeaderCount. Zero disables the check.
+	MaxHeaderBytes int
+	// CloudEventsSource is the CloudEvents source of entries with PresetCloudEvents (default: the request host)
+	CloudEventsSource string
+	// LogFingerprint adds a "fingerprint" field hashing the FingerprintComponents of the request
+	LogFingerprint bool
+	// FingerprintComponents are the parts of the request fingerprint (default: DefaultFingerprintComponents())
+	FingerprintComponents []FingerprintComponent
+	// LogResponseFormat adds a "response_format" field classifying the response Content-Type as json, xml,
+	// html, text or binary. Responses without a Content-Type are not affected.
+	LogResponseFormat bool
+	// LogAllocBytes adds an "alloc_bytes" field with the bytes allocated by the process while the handler
+	// ran, which includes concurrent requests. Reading memory statistics stops the world twice per
+	// request: keep it for profiling. With SlowRequestThreshold set, only slow requests carry the field.
+	LogAllocBytes bool
+	// LogTLSServerName adds a "tls_server_name" field with the SNI server name of TLS requests, when sent
+	LogTLSServerName bool
+	// LogCrossSite adds a "cross_site" field telling whether the host of the Origin header, or else of the
+	// Referer header, differs from the request host
+	LogCrossSite bool
+	// OmitUnknownCrossSite omits "cross_site" for requests with neither header instead of logging them
+	// as same-site
+	OmitUnknownCrossSite bool
+	// LogHandlerFunc adds a "handler_func" field with the Go function name of the route handler, not of the
+	// middleware wrapping it, as recorded by echo in the route Name. Unmatched requests are not affected.
+	LogHandlerFunc bool
+	// SuppressClientStacktraces disables stack traces on entries of client errors (4xx) for loggers built
+	// with zap.AddStacktrace at warn level or below; server errors keep them
+	SuppressClientStacktraces bool
+	// LogETag adds an "etag" field with the response ETag header, when set
+	LogETag bool
+	// LogRouteMatched adds a "route_matched" field telling whether a route matched the request, to tell
+	// unknown routes from handlers responding 404
+	LogRouteMatched bool
+	// OrderedFields nests the standard fields in a single object under EntryKey whose keys are always
+	// written in the same order: remote_ip, latency, host, request, status, size, user_agent (or the
+	// order of the Preset)
+	OrderedFields bool
+	// LogQueryParamCount adds a "query_param_count" field with the number of distinct query parameter
+	// names, a repeated name counting once
+	LogQueryParamCount bool
+	// KeepAliveReusedHeader names a request header set by a proxy to a boolean telling whether the
+	// request reused a keep-alive connection, logged as "keepalive_reused". Invalid values are ignored.
+	KeepAliveReusedHeader string
+	// LatencyBuckets adds a "latency_bucket" field labelling the latency fast, acceptable, slow or critical
+	// for SLO dashboards. Nil disables the field.
+	LatencyBuckets *LatencyBuckets
+	// LogServerUptime adds a "server_uptime_s" field with the seconds elapsed between the creation of the
+	// middleware, usually at process start, and the request
+	LogServerUptime bool
+	// LogAPIVersion adds an "api_version" field with the version requested through the Accept header vendor
+	// media type (application/vnd.myapi.v2+json) or version parameter. Unversioned requests are not affected.
+	LogAPIVersion bool
+	// PanicInfoKey is the context key a recover middleware stores the recovered panic under, as a PanicInfo
+	// or the recovered value. When set, "panic_recovered", "panic_message" and "panic_stack" are logged.
+	PanicInfoKey string
+	// MaxEntryBytes caps the estimated size of an entry: when over, the largest string fields are truncated
+	// to fit and a "truncated" field is added. The fields identifying the request (request, request_id,
+	// remote_ip, host and latency, or their counterparts in the Preset or Values) are kept, except those
+	// of a FieldMapper, and strings aren't cut below 32 bytes, so the cap may not be met. Fields added to
+	// the logger with With are not counted. Zero disables the cap.
+	MaxEntryBytes int
+	// LogFirstRequest writes an additional "First request" entry, with a "first_request" field, for the
+	// first request handled successfully (status below 400) by the middleware, as a readiness signal
+	LogFirstRequest bool
+	// LogSizeMismatch flags responses whose Content-Length header differs from the bytes written with
+	// "size_mismatch", "declared_size" and "actual_size" fields, logged at least at warn level. HEAD
+	// requests and 204 or 304 responses are not checked.
+	LogSizeMismatch bool
+	// FieldPrefix is prepended to the keys of the fields added by the middleware, e.g. "access_" gives
+	// "access_status". The error field and the fields of the logger are left unchanged.
+	FieldPrefix string
+	// PrefixCustomFields applies FieldPrefix to the custom fields too
+	PrefixCustomFields bool
+	// Values selects the standard fields like echo's RequestLoggerConfig value flags, replacing the fields
+	// of the Preset
+	Values *RequestLoggerValues
+	// Skipper skips logging the requests it returns true for, the handler still runs
+	Skipper func(c echo.Context) bool
+	// SkipPaths skips logging requests whose path is one of these, e.g. health checks, in addition to Skipper
+	SkipPaths []string
+	// LogRequestBody adds a "request_body" field with up to MaxBodySize bytes of the request body read by
+	// the handler, and "request_body_truncated" when it was longer
+	LogRequestBody bool
+	// LogResponseBody adds a "response_body" field with up to MaxBodySize bytes of the response body, and
+	// "response_body_truncated" when it was longer
+	LogResponseBody bool
+	// MaxBodySize is the number of bytes of each body kept for LogRequestBody and LogResponseBody, which
+	// also applies to DebugBodyHeader when combined (default: echozap.DefaultMaxBodySize)
+	MaxBodySize int
+	// BodyContentTypes restricts LogRequestBody and LogResponseBody to bodies whose media type starts
+	// with one of these, e.g. "application/json" or "text/". Empty logs every body.
+	BodyContentTypes []string
+	// BodyRedactor returns the body to log in place of the captured one, e.g. with secrets masked
+	BodyRedactor func(body []byte, c echo.Context) []byte
+	// IncludeTraceContext adds "trace_id", "span_id", "parent_span_id" and "trace_flags" fields with the
+	// trace context of the request, as returned by TraceExtractor, to join logs with traces
+	IncludeTraceContext bool
+	// TraceExtractor returns the trace context of a request (default: TraceFromTraceParent, which only
+	// knows the caller's span from the traceparent header). The span of the request itself, as started by
+	// OpenTelemetry's otelecho, takes an extractor reading it from the request context: echozap can't
+	// depend on OpenTelemetry, which requires more recent versions of Go and zap.
+	TraceExtractor TraceExtractor
+	// FieldMapper returns the standard fields of a request, replacing those of the Preset and Values, to
+	// add, remove, rename or reorder them. It is called after the handler returned err; the remaining
+	// fields enabled by the options are appended to its fields.
+	FieldMapper func(c echo.Context, start time.Time, err error) []zapcore.Field
+	// NumericLatency logs the "latency" field as a zap.Duration, encoded by the EncodeDuration of the
+	// encoder config, instead of a string, so it can be aggregated. CompactLatency is ignored.
+	NumericLatency bool
+	// LogLatencyMs adds a "latency_ms" field with the latency in milliseconds, as a float
+	LogLatencyMs bool
+	// LogStartEndTime adds "start_time" and "end_time" fields delimiting the request, encoded by the
+	// EncodeTime of the encoder config
+	LogStartEndTime bool
+	// LogRequestHeaders are the request headers to log under "request_headers". Authorization, Cookie and
+	// Proxy-Authorization values are masked. Ignored when DebugQueryParam logs all of them.
+	LogRequestHeaders []string
+	// LogResponseHeaders are the response headers to log under "response_headers", Set-Cookie values
+	// being masked
+	LogResponseHeaders []string
+	// HeaderRedactor returns the value to log for a header selected with LogRequestHeaders or
+	// LogResponseHeaders, e.g. with tokens masked. Multiple values are passed joined with ", ".
+	HeaderRedactor func(name, value string) string
+	// SuccessSampleRate samples the entries of successful requests (status below 400), keeping this
+	// fraction of them as NewRateSampler does, while failed requests are always logged. Entries carry
+	// a "sampled" field like with Sampler, which applies first when both are set. Zero or 1 and above
+	// disable it.
+	SuccessSampleRate float64
+	// GenerateRequestID generates a request id for requests without one, in the X-Request-ID request or
+	// response header, sets it on the response header and stores it for RequestID, so correlated logs
+	// don't need echo's RequestID middleware
+	GenerateRequestID bool
+	// RequestIDGenerator returns a new request id (default: a random UUID)
+	RequestIDGenerator func() string
+	// Metrics records the metrics of every request handled, from the same measurements as the entry
+	Metrics MetricsRecorder
+	// Before is called before the handler, e.g. to annotate the context or start timers
+	Before func(c echo.Context)
+	// After is called with the fields of the entry, custom fields included, right before it is written
+	// and returns the fields to write, or nil to suppress the entry. MaxEntryBytes applies to its result.
+	// The slice is reused by later requests: copy it to keep it.
+	After func(c echo.Context, err error, fields []zapcore.Field) []zapcore.Field
+	// DetectClientDisconnect logs requests whose client went away, the request context being canceled or the
+	// handler returning context.Canceled, with a "Client Disconnected" message and a "canceled" field, at
+	// ClientDisconnectLevel, whatever the status
+	DetectClientDisconnect bool
+	// ClientDisconnectLevel is the level of the entries of disconnected clients (default: Info)
+	ClientDisconnectLevel zapcore.Level
+	// AnonymizeIP zeroes the last octet of IPv4 client addresses and the last 80 bits of IPv6 ones in the
+	// logged "remote_ip" (or the field of the Preset) and "remote_addr", in the X-Forwarded-For and
+	// X-Real-Ip headers logged by LogRequestHeaders, Values.LogHeaders or DebugQueryParam, and in the
+	// LogEvent.RemoteIP of EventSink and Metrics. ZapRecover has its own RecoverOptions.AnonymizeIP.
+	AnonymizeIP bool
+	// FieldScrubber returns the field to log in place of each entry field, custom fields and the error
+	// included, e.g. to mask or hash personal data. Fields nested in objects and those added to the logger
+	// with With are not passed to it.
+	FieldScrubber func(field zapcore.Field) zapcore.Field
+	// LogQuery adds a "query" object with the values of every query parameter, those of SensitiveParams
+	// being redacted. Ignored when DebugQueryParam logs the query.
+	LogQuery bool
+	// StripQuery logs the request URI without its query string in the "request" field (or the URI field of
+	// the Preset)
+	StripQuery bool
+	// LogRoute adds a "route" field with the matched route pattern (e.g. /users/:id), to aggregate requests
+	// per route. Unmatched requests are not affected, nor requests logged with InjectScopedLogger whose
+	// logger already carries it. Use LogHandlerFunc for the handler name.
+	LogRoute bool
+	// LogPathParams adds a "path_params" object with the path parameters of the matched route
+	LogPathParams bool
+	// UserResolver returns the fields identifying the authenticated user of a request, such as JWTSubject
+	// or ContextUser. It is called once the handler chain, authentication middleware included, returned.
+	UserResolver func(c echo.Context) []zapcore.Field
+	// LogConnections logs the connections upgraded with "Connection: Upgrade", such as WebSockets, and the
+	// server-sent event streams of "text/event-stream" responses with a "Connection opened" entry as soon
+	// as they are established. The access-log entry written when they end reads "Connection closed" and
+	// adds their "duration" and the "bytes_in" and "bytes_out" transferred, counted on the connection once
+	// hijacked. Both entries carry a "connection_type" field, the upgrade protocol or "sse", and clients
+	// closing them are not reported by DetectClientDisconnect.
+	LogConnections bool
+	// Clock returns the current time, used to measure requests (default: time.Now)
+	Clock func() time.Time
+
+	// console switches the entry message to a compact human readable form (set by ZapLoggerDev)
+	console bool
 }
 
 // ZapLogger is a middleware and zap to provide an "access log" like logging for each request.
 func ZapLogger(options *Options) echo.MiddlewareFunc {
-	logger := options.Logger
-
+	fieldsKey, loggerKey := defaultKeys()
 	if options.CustomFieldsKey == "" {
-		options.CustomFieldsKey = DefaultCustomFieldsKey
+		options.CustomFieldsKey = fieldsKey
 	}
 	if options.CustomLoggerKey == "" {
-		options.CustomLoggerKey = DefaultCustomLoggerKey
+		options.CustomLoggerKey = loggerKey
+	}
+	if options.EntryKey == "" {
+		options.EntryKey = DefaultEntryKey
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	if len(options.CorrelationSources) == 0 {
+		options.CorrelationSources = DefaultCorrelationSources()
+	}
+	if options.CorrelationIDHeader == "" {
+		options.CorrelationIDHeader = DefaultCorrelationIDHeader
+	}
+	if len(options.FingerprintComponents) == 0 {
+		options.FingerprintComponents = DefaultFingerprintComponents()
+	}
+	if options.RequestIDGenerator == nil {
+		options.RequestIDGenerator = newUUID
+	}
+	if options.TraceExtractor == nil {
+		options.TraceExtractor = TraceFromTraceParent
+	}
+	if options.DeviceClassifier == nil {
+		options.DeviceClassifier = ClassifyDevice
+	}
+	if options.FallbackLogger == nil {
+		options.FallbackLogger = zap.New(zapcore.NewCore(
+			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			zapcore.Lock(os.Stderr),
+			zapcore.DebugLevel,
+		))
+	}
+
+	var errorLogger *zap.Logger
+	if options.ErrorSampleFirst > 0 {
+		errorLogger = newErrorSampler(options.Logger, options.ErrorSampleFirst, options.ErrorSampleThereafter)
+	}
+
+	var started time.Time
+	if options.LogServerUptime {
+		started = options.Clock()
+	}
+
+	var firstRequest sync.Once
+
+	var entries *coalescer
+	if options.CoalesceWindow > 0 {
+		entries = newCoalescer(options, options.CoalesceWindow)
+	}
+
+	skipper := newSkipper(options.Skipper, options.SkipPaths)
+
+	// when the entry level only depends on the status, requests whose entry is disabled skip building it
+	fixedLevel := options.LevelResolver == nil && !options.DetectClientDisconnect && entries == nil &&
+		!options.LogFirstRequest && options.After == nil
+	escalated := options.SlowRequestThreshold > 0 || options.MaxHeaderCount > 0 || options.MaxHeaderBytes > 0 ||
+		options.LogSizeMismatch
+
+	var successSampler Sampler
+	if options.SuccessSampleRate > 0 && options.SuccessSampleRate < 1 {
+		successSampler = NewRateSampler(options.SuccessSampleRate)
 	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if skipper != nil && skipper(c) {
+				return next(c)
+			}
+			c.Set(fieldsKeyKey, options.CustomFieldsKey)
+			c.Set(fieldsLockKey, &sync.Mutex{})
+
+			if options.GenerateRequestID && requestID(c) == "" {
+				id := options.RequestIDGenerator()
+				c.Set(requestIDKey, id)
+				c.Response().Header().Set(echo.HeaderXRequestID, id)
+			}
+
+			logger := options.Logger
+			// sampled tells whether the logger derives from Logger, whose error entries are sampled
+			sampled := true
 			customerLogger := getLoggerFromContext(c, options.CustomLoggerKey)
 			if customerLogger != nil {
 				logger = customerLogger
+				sampled = false
+			}
+
+			var correlation string
+			if options.LogCorrelationID {
+				correlation = correlationID(c, options.CorrelationSources)
+				c.Response().Header().Set(options.CorrelationIDHeader, correlation)
+			}
+
+			var scoped []zapcore.Field
+			if options.InjectScopedLogger {
+				scoped = []zapcore.Field{
+					zap.String("request_id", requestID(c)),
+					zap.String("method", c.Request().Method),
+					zap.String("route", matchedRoute(c)),
+				}
+				if options.LogCorrelationID {
+					scoped = append(scoped, zap.String("correlation_id", correlation))
+				}
+				logger = logger.With(scoped...)
+			}
+
+			handlerLogger := logger
+			if options.InjectScopedLogger {
+				handlerLogger = logger.With(zap.String("remote_ip", remoteIP(options, c)))
+				c.Set(options.CustomLoggerKey, handlerLogger)
+				c.Set(scopedLoggerKey, handlerLogger)
+			}
+
+			if options.InjectContextLogger {
+				c.SetRequest(c.Request().WithContext(withLogger(c.Request().Context(), handlerLogger)))
+			}
+
+			start := options.Clock()
+
+			if options.EmitServerTiming {
+				res := c.Response()
+				res.Before(func() {
+					addServerTiming(res.Header(), options.Clock().Sub(start))
+				})
+			}
+
+			debugBody := options.DebugBodyHeader != ""
+			captureRequest := options.LogRequestBody || debugBody
+			captureResponse := options.LogResponseBody || debugBody
+
+			var body *bodyReader
+			if options.LogBodyReadTime || options.LogUnreadBody || captureRequest || options.LogConnections {
+				if req := c.Request(); req.Body != nil && req.Body != http.NoBody {
+					body = &bodyReader{ReadCloser: req.Body}
+					if options.LogBodyReadTime {
+						body.clock = options.Clock
+					}
+					if captureRequest {
+						body.capture = newLimitedBuffer(bodyLimit(options, options.LogRequestBody))
+					}
+					req.Body = body
+				}
+			}
+
+			var writer *responseWriter
+			if options.LogWriteTiming || options.LogFlushCount || captureResponse || options.LogConnections {
+				res := c.Response()
+				writer = newResponseWriter(res.Writer, options.Clock)
+				if captureResponse {
+					writer.capture = newLimitedBuffer(bodyLimit(options, options.LogResponseBody))
+				}
+				res.Writer = writer
+			}
+
+			// connections are logged as soon as they are hijacked or the event stream is committed
+			var connType string
+			if options.LogConnections {
+				opened := func(kind string) {
+					connType = kind
+					writeEntry(options, logger, zapcore.InfoLevel, "Connection opened", connectionOpenedFields(options, c, kind))
+				}
+				if kind := upgradeType(c.Request()); kind != "" {
+					writer.onHijack = func() { opened(kind) }
+				}
+				res := c.Response()
+				res.Before(func() {
+					if isEventStream(res.Header().Get(echo.HeaderContentType)) {
+						opened(connectionTypeSSE)
+					}
+				})
+			}
+
+			// the sentinel header is read and removed right before the response is committed
+			var debugBodyRequest bool
+			if debugBody {
+				res := c.Response()
+				res.Before(func() {
+					debugBodyRequest = debugBodyRequested(res.Header(), options.DebugBodyHeader)
+					res.Header().Del(options.DebugBodyHeader)
+				})
 			}
 
-			start := time.Now()
+			if options.Before != nil {
+				options.Before(c)
+			}
+
+			var allocated uint64
+			if options.LogAllocBytes {
+				allocated = totalAlloc()
+			}
 
 			err := next(c)
 			if err != nil {
 				c.Error(err)
 			}
 
+			if options.LogAllocBytes {
+				allocated = totalAlloc() - allocated
+			}
+			end := options.Clock()
+			latency := end.Sub(start)
+
+			if options.EmitServerTiming && !c.Response().Committed {
+				addServerTiming(c.Response().Header(), latency)
+			}
+
+			if options.LoggerResolver != nil {
+				// the resolved logger is scoped too, the entry relies on it for the scoped fields
+				if resolved := options.LoggerResolver(c); resolved != nil {
+					logger = resolved
+					sampled = false
+					if options.InjectScopedLogger {
+						logger = resolved.With(scoped...)
+					}
+				}
+			}
+
 			req := c.Request()
 			res := c.Response()
 
-			fields := []zapcore.Field{
-				zap.String("remote_ip", c.RealIP()),
-				zap.String("latency", time.Since(start).String()),
-				zap.String("host", req.Host),
-				zap.String("request", fmt.Sprintf("%s %s", req.Method, req.RequestURI)),
-				zap.Int("status", res.Status),
-				zap.Int64("size", res.Size),
-				zap.String("user_agent", req.UserAgent()),
+			n := res.Status
+			if connType != "" && writer.hijacked() && !res.Committed {
+				n = http.StatusSwitchingProtocols
+			}
+			unknown := n == 0
+			if unknown && options.NormalizeUnknownStatus {
+				n = http.StatusOK
 			}
 
-			// add custom fields if provided and valid
-			customFields, ok := c.Get(options.CustomFieldsKey).([]zapcore.Field)
-			if ok {
-				fields = append(fields, customFields...)
+			if fixedLevel {
+				level := statusLevel(options.LevelRules, n)
+				if escalated && level < zapcore.WarnLevel {
+					level = zapcore.WarnLevel
+				}
+				_, overridden := levelOverride(c)
+				_, audited := auditRecord(c)
+				if !overridden && !audited && !logger.Core().Enabled(level) {
+					recordEvent(options, c, start, latency, n, requestID(c), err)
+					return nil
+				}
+			}
+
+			// the fields of entries written synchronously are reused by the next requests, unless nested in an
+			// object which cores may encode later
+			var pooled *[]zapcore.Field
+			if entries == nil && !options.OrderedFields && options.Preset != PresetCloudEvents {
+				pooled = fieldsPool.Get().(*[]zapcore.Field)
+				defer releaseFields(pooled)
+			}
+
+			var fields []zapcore.Field
+			if pooled != nil {
+				fields = *pooled
+			}
+			if options.FieldMapper != nil {
+				fields = append(fields, options.FieldMapper(c, start, err)...)
+			} else {
+				fields = standardFields(fields, options, c, n, latency)
+			}
+			if options.OrderedFields {
+				fields = []zapcore.Field{zap.Object(options.EntryKey, fieldsObject(fields))}
+			}
+
+			fields = append(fields, options.StaticFields...)
+
+			if unknown && options.NormalizeUnknownStatus {
+				fields = append(fields, zap.Bool("status_unknown", true))
+			}
+
+			if options.LogTimestamps {
+				fields = append(fields,
+					zap.String("received_at", start.Format(time.RFC3339Nano)),
+					zap.String("completed_at", end.Format(time.RFC3339Nano)),
+				)
+			}
+
+			if options.LogStartEndTime {
+				fields = append(fields, zap.Time("start_time", start), zap.Time("end_time", end))
+			}
+
+			if options.LogLatencyMs && !latencyOmitted(options, latency) {
+				fields = append(fields, zap.Float64("latency_ms", milliseconds(latency)))
+			}
+
+			if options.LogErrorHandled {
+				fields = append(fields, zap.Bool("error_handled_by_middleware", err != nil))
+			}
+
+			if options.LogLatencyBudget {
+				if deadline, ok := req.Context().Deadline(); ok {
+					fields = append(fields, zap.Float64("latency_budget_pct", budgetPercent(latency, deadline.Sub(start))))
+				}
+			}
+
+			if options.LogTimeout {
+				if deadline, ok := req.Context().Deadline(); ok {
+					fields = append(fields, zap.Float64("timeout_ms", milliseconds(deadline.Sub(start))))
+				}
+			}
+
+			if options.LogRequestKind {
+				fields = append(fields, zap.String("request_kind", requestKind(req.Header.Get(echo.HeaderContentType))))
+			}
+
+			if options.LogErrorChain && err != nil {
+				fields = append(fields, zap.Strings("error_chain", errorChain(err)))
+			}
+
+			if options.LogStatusSource {
+				if source := statusSource(c); source != "" {
+					fields = append(fields, zap.String("status_source", source))
+				}
+			}
+
+			if options.LogAuthScheme {
+				fields = append(fields, zap.String("auth_scheme", authScheme(req.Header.Get(echo.HeaderAuthorization))))
+			}
+
+			if options.LogPathHash {
+				fields = append(fields, zap.String("path_hash", hashString(options.PathHashAlgorithm, req.URL.Path)))
+			}
+
+			if options.LogBodyReadTime && body != nil {
+				fields = append(fields, zap.Float64("body_read_ms", milliseconds(body.elapsed)))
+			}
+
+			if options.LogUnreadBody && body != nil && req.ContentLength >= 0 {
+				unread := req.ContentLength - body.bytesRead
+				if unread < 0 {
+					unread = 0
+				}
+				fields = append(fields, zap.Int64("unread_body_bytes", unread))
+			}
+
+			if options.LogRequestBody && body != nil && bodyAllowed(options.BodyContentTypes, req.Header.Get(echo.HeaderContentType)) {
+				fields = append(fields, bodyFields(options, c, "request_body", body.capture)...)
+			}
+
+			if options.LogResponseBody && bodyAllowed(options.BodyContentTypes, res.Header().Get(echo.HeaderContentType)) {
+				fields = append(fields, bodyFields(options, c, "response_body", writer.capture)...)
+			}
+
+			if debugBody && (debugBodyRequest || debugBodyRequested(res.Header(), options.DebugBodyHeader)) {
+				if body != nil && !options.LogRequestBody {
+					fields = append(fields, zap.ByteString("request_body", body.capture.Bytes()))
+				}
+				if !options.LogResponseBody {
+					fields = append(fields, zap.ByteString("response_body", writer.capture.Bytes()))
+				}
+			}
+
+			if options.LogInternalError {
+				if internal := internalError(err); internal != nil {
+					fields = append(fields, zap.String("internal_error", internal.Error()))
+				}
+			}
+
+			if options.LogFlushCount {
+				fields = append(fields, zap.Int("flush_count", writer.flushes))
+			}
+
+			if connType != "" {
+				var bytesIn int64
+				bytesOut := res.Size
+				if body != nil {
+					bytesIn = body.bytesRead
+				}
+				if writer.hijacked() {
+					bytesIn, bytesOut = writer.conn.bytes()
+				}
+				fields = append(fields,
+					zap.String("connection_type", connType),
+					zap.Duration("duration", latency),
+					zap.Int64("bytes_in", bytesIn),
+					zap.Int64("bytes_out", bytesOut),
+				)
+			}
+
+			if options.LogWriteTiming && writer.written() {
+				fields = append(fields,
+					zap.Float64("ttfb_ms", milliseconds(writer.firstWrite.Sub(start))),
+					zap.Float64("write_duration_ms", milliseconds(writer.lastWrite.Sub(writer.firstWrite))),
+				)
+			}
+
+			if len(options.LogBaggageKeys) > 0 {
+				if baggage := parseBaggage(req.Header, options.LogBaggageKeys); len(baggage) > 0 {
+					fields = append(fields, zap.Object("baggage", baggageObject(baggage)))
+				}
+			}
+
+			if options.LogConditional {
+				conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get(echo.HeaderIfModifiedSince) != ""
+				fields = append(fields, zap.Bool("if_none_match", conditional))
+			}
+
+			if options.LogGRPCStatus {
+				if code, err := strconv.Atoi(res.Header().Get("Grpc-Status")); err == nil {
+					fields = append(fields, zap.Int("grpc_status", code))
+				}
+			}
+
+			if options.RedirectCountHeader != "" {
+				if count, ok := headerInt(req.Header, options.RedirectCountHeader); ok {
+					fields = append(fields, zap.Int("redirect_count", count))
+				}
+			}
+
+			if options.LogLocale {
+				if locale := preferredLanguage(req.Header.Get("Accept-Language")); locale != "" {
+					fields = append(fields, zap.String("locale", locale))
+				}
+			}
+
+			if options.URLObject {
+				fields = append(fields, zap.Object("url", urlObject{
+					scheme: c.Scheme(),
+					host:   req.Host,
+					path:   loggedPath(options, req),
+					query:  redactQuery(req.URL.Query(), req.URL.RawQuery, options.SensitiveParams),
+				}))
+			}
+
+			if options.LogMutation {
+				fields = append(fields, zap.Bool("is_mutation", isMutation(req.Method)))
+			}
+
+			if options.LogCorrelationID && !options.InjectScopedLogger {
+				fields = append(fields, zap.String("correlation_id", correlation))
+			}
+
+			if options.LogRouteGroup {
+				if group := routeGroup(c); group != "" {
+					fields = append(fields, zap.String("route_group", group))
+				}
+			}
+
+			if options.RetryAttemptHeader != "" {
+				if attempt, ok := headerInt(req.Header, options.RetryAttemptHeader); ok && attempt > 0 {
+					fields = append(fields, zap.Int("retry_attempt", attempt))
+				}
+			}
+
+			if options.LogDeviceType {
+				if device := options.DeviceClassifier(req.UserAgent()); device != "" {
+					fields = append(fields, zap.String("device_type", device))
+				}
+			}
+
+			slow := options.SlowRequestThreshold > 0 && latency > options.SlowRequestThreshold
+			if slow {
+				fields = append(fields,
+					zap.Bool("slow_request", true),
+					zap.Duration("slow_threshold", options.SlowRequestThreshold),
+				)
+			}
+
+			if options.LogSlowDiagnostics && slow {
+				fields = append(fields, zap.Int("num_goroutines", runtime.NumGoroutine()))
+			}
+
+			if options.LogAllocBytes && (slow || options.SlowRequestThreshold <= 0) {
+				fields = append(fields, zap.Uint64("alloc_bytes", allocated))
+			}
+
+			if options.ClockSkewHeader != "" {
+				if sent, ok := headerTime(req.Header, options.ClockSkewHeader); ok {
+					fields = append(fields, zap.Float64("clock_skew_ms", milliseconds(sent.Sub(start))))
+				}
+			}
+
+			if options.ValidationErrorsKey != "" {
+				if errs := validationErrors(c.Get(options.ValidationErrorsKey)); len(errs) > 0 {
+					fields = append(fields, zap.Array("validation_errors", errs))
+				}
+			}
+
+			if options.LogCacheControl {
+				if cacheControl := res.Header().Get("Cache-Control"); cacheControl != "" {
+					fields = append(fields, zap.String("cache_control", cacheControl))
+				}
+			}
+
+			if options.LogETag {
+				if etag := res.Header().Get("ETag"); etag != "" {
+					fields = append(fields, zap.String("etag", etag))
+				}
+			}
+
+			verbose := options.DebugQueryParam != "" && isTruthy(req.URL.Query().Get(options.DebugQueryParam))
+			if verbose {
+				fields = append(fields, verboseFields(options, c)...)
+			}
+
+			if options.LogQuery && !verbose && len(req.URL.RawQuery) > 0 {
+				fields = append(fields, zap.Object("query", queryObject{query: req.URL.Query(), sensitive: options.SensitiveParams}))
+			}
+
+			if len(options.LogRequestHeaders) > 0 && !verbose {
+				fields = append(fields, zap.Object("request_headers", selectedHeadersObject{
+					header:    req.Header,
+					names:     options.LogRequestHeaders,
+					redactor:  options.HeaderRedactor,
+					anonymize: options.AnonymizeIP,
+				}))
+			}
+
+			if len(options.LogResponseHeaders) > 0 {
+				fields = append(fields, zap.Object("response_headers", selectedHeadersObject{
+					header:   res.Header(),
+					names:    options.LogResponseHeaders,
+					redactor: options.HeaderRedactor,
+				}))
+			}
+
+			if options.LogConnReused {
+				if reused, ok := connReused(c, options.ConnTracker); ok {
+					fields = append(fields, zap.Bool("conn_reused", reused))
+				}
+			}
+
+			if options.LogFingerprint {
+				fields = append(fields, zap.String("fingerprint", Fingerprint(c, options.FingerprintComponents...)))
+			}
+
+			if options.LogResponseFormat {
+				if format := responseFormat(res.Header().Get(echo.HeaderContentType)); format != "" {
+					fields = append(fields, zap.String("response_format", format))
+				}
+			}
+
+			if options.LogTLSServerName && req.TLS != nil && req.TLS.ServerName != "" {
+				fields = append(fields, zap.String("tls_server_name", req.TLS.ServerName))
+			}
+
+			if options.LogCrossSite {
+				if crossSite, ok := isCrossSite(req); ok || !options.OmitUnknownCrossSite {
+					fields = append(fields, zap.Bool("cross_site", crossSite))
+				}
+			}
+
+			if options.UserResolver != nil {
+				fields = append(fields, options.UserResolver(c)...)
+			}
+
+			if options.LogRoute && !options.InjectScopedLogger {
+				if route := matchedRoute(c); route != "" {
+					fields = append(fields, zap.String("route", route))
+				}
+			}
+
+			if options.LogPathParams && len(c.ParamNames()) > 0 {
+				fields = append(fields, zap.Object("path_params", newParamsObject(c)))
+			}
+
+			if options.LogHandlerFunc {
+				if name := handlerFunc(c); name != "" {
+					fields = append(fields, zap.String("handler_func", name))
+				}
+			}
+
+			if options.LogRouteMatched {
+				fields = append(fields, zap.Bool("route_matched", matchedRoute(c) != ""))
+			}
+
+			if options.LogQueryParamCount {
+				fields = append(fields, zap.Int("query_param_count", len(req.URL.Query())))
+			}
+
+			if options.KeepAliveReusedHeader != "" {
+				if reused, ok := headerBool(req.Header, options.KeepAliveReusedHeader); ok {
+					fields = append(fields, zap.Bool("keepalive_reused", reused))
+				}
+			}
+
+			if options.LatencyBuckets != nil {
+				fields = append(fields, zap.String("latency_bucket", options.LatencyBuckets.bucket(latency)))
+			}
+
+			if options.LogServerUptime {
+				fields = append(fields, zap.Float64("server_uptime_s", start.Sub(started).Seconds()))
+			}
+
+			if options.LogAPIVersion {
+				if version := apiVersion(req.Header.Get(echo.HeaderAccept)); version != "" {
+					fields = append(fields, zap.String("api_version", version))
+				}
+			}
+
+			if options.PanicInfoKey != "" {
+				if info, ok := panicInfo(c.Get(options.PanicInfoKey)); ok {
+					fields = append(fields,
+						zap.Bool("panic_recovered", true),
+						zap.String("panic_message", panicMessage(info.Value)),
+					)
+					if len(info.Stack) > 0 {
+						fields = append(fields, zap.ByteString("panic_stack", info.Stack))
+					}
+				}
 			}
 
-			id := req.Header.Get(echo.HeaderXRequestID)
-			if id == "" {
-				id = res.Header().Get(echo.HeaderXRequestID)
+			if options.IncludeTraceContext {
+				if tc, ok := options.TraceExtractor(c); ok {
+					fields = append(fields, zap.String("trace_id", tc.TraceID))
+					if tc.SpanID != "" {
+						fields = append(fields, zap.String("span_id", tc.SpanID))
+					}
+					if tc.ParentSpanID != "" {
+						fields = append(fields, zap.String("parent_span_id", tc.ParentSpanID))
+					}
+					fields = append(fields, zap.String("trace_flags", tc.Flags))
+				}
+			}
+
+			if options.LogStatusClass {
+				fields = append(fields, zap.Int("status_class", n/100))
+			}
+
+			if options.LogOperation {
+				fields = append(fields, zap.String("operation", operation(req.Method, matchedRoute(c))))
+			}
+
+			if options.LogStatusText {
+				if text := http.StatusText(n); text != "" {
+					fields = append(fields, zap.String("status_text", text))
+				}
+			}
+
+			if options.LogEffectiveHost {
+				fields = append(fields, zap.String("effective_host", req.Host))
+			}
+
+			if options.LogRawHostHeader {
+				if host := rawHostHeader(req); host != "" {
+					fields = append(fields, zap.String("raw_host_header", host))
+				}
+			}
+
+			if options.LogCompressed {
+				fields = append(fields, zap.Bool("compressed", isCompressed(res.Header().Get(echo.HeaderContentEncoding))))
+			}
+
+			if options.LogRemoteAddr {
+				addr := stripPort(req.RemoteAddr)
+				if options.AnonymizeIP {
+					addr = anonymizeIP(addr)
+				}
+				fields = append(fields, zap.String("remote_addr", addr))
+			}
+
+			if options.EntryMarshaler != nil {
+				fields = []zapcore.Field{zap.Object(options.EntryKey, options.EntryMarshaler(c))}
+			}
+
+			// the scoped logger already carries the request id, Values selects it itself
+			id := requestID(c)
+			if !options.InjectScopedLogger && options.Values == nil {
 				fields = append(fields, zap.String("request_id", id))
 			}
+			if resID := res.Header().Get(echo.HeaderXRequestID); resID != "" && resID != id {
+				fields = append(fields, zap.Bool("request_id_mismatch", true))
+			}
 
-			n := res.Status
 			text := http.StatusText(n)
+			if options.console {
+				text = fmt.Sprintf("%d %s %s %s", n, req.Method, requestTarget(options, req), formatLatency(latency))
+			}
+
+			level := statusLevel(options.LevelRules, n)
+			if options.DetectDeadLinks && n == http.StatusNotFound {
+				deadLink := isSameHost(req.Referer(), req.Host)
+				if deadLink {
+					fields = append(fields, zap.Bool("dead_link", true))
+				}
+				if _, ok := ruleLevel(options.LevelRules, n); !ok && !deadLink {
+					level = zapcore.InfoLevel
+				}
+			}
+			if options.LevelResolver != nil {
+				level = options.LevelResolver(c, err)
+			}
+			if options.MaxHeaderCount > 0 || options.MaxHeaderBytes > 0 {
+				count, size := headerSize(req.Header)
+				if (options.MaxHeaderCount > 0 && count > options.MaxHeaderCount) ||
+					(options.MaxHeaderBytes > 0 && size > options.MaxHeaderBytes) {
+					fields = append(fields, zap.Bool("large_headers", true))
+					if level < zapcore.WarnLevel {
+						level = zapcore.WarnLevel
+					}
+				}
+			}
+			if options.LogSizeMismatch && req.Method != http.MethodHead && n != http.StatusNoContent && n != http.StatusNotModified {
+				if declared, err := strconv.ParseInt(res.Header().Get(echo.HeaderContentLength), 10, 64); err == nil && declared != res.Size {
+					fields = append(fields,
+						zap.Bool("size_mismatch", true),
+						zap.Int64("declared_size", declared),
+						zap.Int64("actual_size", res.Size),
+					)
+					if level < zapcore.WarnLevel {
+						level = zapcore.WarnLevel
+					}
+				}
+			}
+
+			if slow && level < zapcore.WarnLevel {
+				level = zapcore.WarnLevel
+			}
+
+			canceled := options.DetectClientDisconnect && connType == "" && clientDisconnected(c, err)
+			if canceled {
+				fields = append(fields, zap.Bool("canceled", true))
+				level = options.ClientDisconnectLevel
+			}
+
+			if override, ok := levelOverride(c); ok {
+				level = override
+			}
+			if errorLogger != nil && sampled && level >= zapcore.ErrorLevel {
+				logger = errorLogger
+				if options.InjectScopedLogger {
+					logger = errorLogger.With(scoped...)
+				}
+			}
+
+			var (
+				msg      string
+				logError bool
+			)
 			switch {
+			case canceled:
+				logError = err != nil
+				msg = "Client Disconnected"
 			case n >= 500:
-				logger.With(zap.Error(err)).Error(fmt.Sprintf("Server: %s", text), fields...)
+				logError = true
+				msg = statusMessage(options, n, "Server", text)
 			case n >= 400:
-				logger.With(zap.Error(err)).Warn(fmt.Sprintf("Client: %s", text), fields...)
+				logError = true
+				if options.SuppressClientStacktraces {
+					logger = logger.WithOptions(zap.AddStacktrace(zapcore.FatalLevel))
+				}
+				msg = statusMessage(options, n, "Client", text)
 			case n >= 300:
-				logger.Info(fmt.Sprintf("Redirection: %s", text), fields...)
+				msg = statusMessage(options, n, "Redirection", text)
+			case n == 0:
+				msg = "Unknown status"
+				if options.console {
+					msg = text
+				}
 			default:
-				logger.Info(fmt.Sprintf("Success: %s", text), fields...)
+				msg = statusMessage(options, n, "Success", text)
+			}
+			if connType != "" && n < 400 && !options.console {
+				msg = "Connection closed"
 			}
 
+			keep := true
+			lowPriority := n < 400 && containsString(options.LowPriorityPaths, req.URL.Path)
+			if lowPriority {
+				keep = options.LowPrioritySampler != nil && options.LowPrioritySampler(c)
+			} else if options.Sampler != nil || (successSampler != nil && n < 400) {
+				if options.Sampler != nil {
+					keep = options.Sampler(c)
+				}
+				if keep && successSampler != nil && n < 400 {
+					keep = successSampler(c)
+				}
+				fields = append(fields, zap.Bool("sampled", keep))
+				if !keep && options.LogSampledOut {
+					level = zapcore.DebugLevel
+				}
+			}
+
+			if options.Preset == PresetGCP {
+				fields = append(fields, zap.String("severity", gcpSeverity(level)))
+			}
+
+			customFields, _ := c.Get(options.CustomFieldsKey).([]zapcore.Field)
+
+			if options.LogFieldCount {
+				fields = append(fields, zap.Int("field_count", countFields(fields)+countFields(customFields)))
+			}
+
+			if options.FieldPrefix != "" {
+				prefixFields(options.FieldPrefix, fields)
+				if options.PrefixCustomFields && len(customFields) > 0 {
+					customFields = append([]zapcore.Field(nil), customFields...)
+					prefixFields(options.FieldPrefix, customFields)
+				}
+			}
+
+			// add custom fields if provided and valid, they go last as they may be namespaced
+			if len(customFields) > 0 {
+				if options.CustomFieldsNamespace != "" {
+					fields = append(fields, zap.Namespace(options.CustomFieldsNamespace))
+				}
+				fields = append(fields, customFields...)
+			}
+
+			if options.FieldScrubber != nil {
+				for i := range fields {
+					fields[i] = options.FieldScrubber(fields[i])
+				}
+			}
+
+			suppressed := false
+			if options.After != nil {
+				fields = options.After(c, err, fields)
+				suppressed = fields == nil
+			}
+
+			if options.MaxEntryBytes > 0 {
+				fields = truncateFields(msg, fields, options.MaxEntryBytes, options.FieldPrefix, identifyingFields(options))
+			}
+
+			if options.Preset == PresetCloudEvents {
+				fields = cloudEvent(options, c, id, end, fields)
+			}
+
+			// the error goes at the top level, ahead of the custom fields namespace, as it was added to the
+			// logger before
+			if logError {
+				errorField := zap.Error(err)
+				if options.FieldScrubber != nil {
+					errorField = options.FieldScrubber(errorField)
+				}
+				fields = appendTopLevel(fields, errorField)
+			}
+
+			if !suppressed && (keep || (options.LogSampledOut && !lowPriority)) {
+				if entries != nil {
+					entries.add(coalesceKey{method: req.Method, route: matchedRoute(c), status: n}, logger, level, msg, fields)
+				} else {
+					writeEntry(options, logger, level, msg, fields)
+				}
+			}
+
+			if options.LogFirstRequest && n > 0 && n < 400 {
+				firstRequest.Do(func() {
+					first := appendTopLevel(fields[:len(fields):len(fields)], zap.Bool("first_request", true))
+					writeEntry(options, logger, zapcore.InfoLevel, "First request", first)
+				})
+			}
+
+			if record, ok := auditRecord(c); ok {
+				auditLogger := options.AuditLogger
+				if auditLogger == nil {
+					auditLogger = options.Logger
+				}
+				writeEntry(options, auditLogger, zapcore.InfoLevel, "Audit", auditFields(record, end))
+			}
+
+			recordEvent(options, c, start, latency, n, id, err)
+
 			return nil
 		}
 	}
 }
 
+// clientDisconnected reports whether the client of the request went away: its context was canceled or
+// the handler returned context.Canceled
+func clientDisconnected(c echo.Context, err error) bool {
+	if c.Request().Context().Err() == context.Canceled {
+		return true
+	}
+	return err == context.Canceled || internalError(err) == context.Canceled
+}
+
+// recordEvent sends the event of a request to Options.EventSink and Options.Metrics, when set
+func recordEvent(options *Options, c echo.Context, start time.Time, latency time.Duration, status int, id string, err error) {
+	if options.EventSink == nil && options.Metrics == nil {
+		return
+	}
+
+	event := newLogEvent(options, c, start, latency, status, id, err)
+	if options.EventSink != nil {
+		sendEvent(options.EventSink, options.EventSinkBlocking, event)
+	}
+	if options.Metrics != nil {
+		options.Metrics.RecordRequest(event)
+	}
+}
+
+// newSkipper combines skipper with a skipper for paths, returning nil when nothing is skipped
+func newSkipper(skipper func(echo.Context) bool, paths []string) func(echo.Context) bool {
+	if len(paths) == 0 {
+		return skipper
+	}
+
+	skipped := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		skipped[path] = struct{}{}
+	}
+
+	return func(c echo.Context) bool {
+		if _, ok := skipped[c.Request().URL.Path]; ok {
+			return true
+		}
+		return skipper != nil && skipper(c)
+	}
+}
+
+// writeEntry logs the access-log entry, recovering from panics unless options.FailClosed is set
+func writeEntry(options *Options, logger *zap.Logger, level zapcore.Level, msg string, fields []zapcore.Field) {
+	if !options.FailClosed {
+		defer func() {
+			if r := recover(); r != nil {
+				options.FallbackLogger.Error("echozap: recovered from a panic while logging", zap.Any("panic", r), zap.String("message", msg))
+			}
+		}()
+	}
+
+	if ce := logger.Check(level, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// prefixFields prepends prefix to the keys of fields, in place
+func prefixFields(prefix string, fields []zapcore.Field) {
+	for i := range fields {
+		if fields[i].Type != zapcore.SkipType {
+			fields[i].Key = prefix + fields[i].Key
+		}
+	}
+}
+
+// statusMessages are the entry messages of the known statuses, built once
+var statusMessages = func() map[int]string {
+	messages := make(map[int]string)
+	for status := 100; status < 600; status++ {
+		text := http.StatusText(status)
+		switch {
+		case text == "":
+		case status >= 500:
+			messages[status] = "Server: " + text
+		case status >= 400:
+			messages[status] = "Client: " + text
+		case status >= 300:
+			messages[status] = "Redirection: " + text
+		default:
+			messages[status] = "Success: " + text
+		}
+	}
+	return messages
+}()
+
+// statusMessage builds the entry message for the given status and class
+func statusMessage(options *Options, status int, class, text string) string {
+	if options.console {
+		return text
+	}
+	if msg, ok := statusMessages[status]; ok {
+		return msg
+	}
+	return class + ": " + text
+}
+
+// fieldsPool holds the field slices of entries, to save allocating one per request
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		fields := make([]zapcore.Field, 0, 32)
+		return &fields
+	},
+}
+
+// releaseFields clears the fields of an entry and returns the slice to fieldsPool
+func releaseFields(pooled *[]zapcore.Field) {
+	fields := (*pooled)[:cap(*pooled)]
+	for i := range fields {
+		fields[i] = zapcore.Field{}
+	}
+	*pooled = fields[:0]
+	fieldsPool.Put(pooled)
+}
+
+// notFoundHandlerName is the name echo records for routes served by echo.NotFoundHandler
+var notFoundHandlerName = runtime.FuncForPC(reflect.ValueOf(echo.NotFoundHandler).Pointer()).Name()
+
+// matchedRoute returns the route template that matched the request, or "" when none did.
+// Echo leaves the raw request path in c.Path() for unmatched requests, so the handler is checked instead.
+// Group.Use also registers catch-all routes serving echo.NotFoundHandler behind the group middleware,
+// which are looked up in the route table once the request got a 404.
+func matchedRoute(c echo.Context) string {
+	if reflect.ValueOf(c.Handler()).Pointer() == reflect.ValueOf(echo.NotFoundHandler).Pointer() {
+		return ""
+	}
+	if c.Response().Status == http.StatusNotFound && notFoundRoute(c) {
+		return ""
+	}
+	return c.Path()
+}
+
+// notFoundRoute reports whether the route of the request is served by echo.NotFoundHandler
+func notFoundRoute(c echo.Context) bool {
+	return routeName(c, c.Request().Method, c.Path()) == notFoundHandlerName
+}
+
+// handlerFunc returns the name echo recorded for the handler of the matched route. The handler seen by
+// middleware is a closure of the router, so the route table is searched instead.
+func handlerFunc(c echo.Context) string {
+	route := matchedRoute(c)
+	if route == "" {
+		return ""
+	}
+	return routeName(c, c.Request().Method, route)
+}
+
+// routeKey identifies a route of an echo instance
+type routeKey struct {
+	echo   *echo.Echo
+	method string
+	path   string
+}
+
+// routeNames caches the names echo recorded for the routes, by routeKey, as echo only lists its routes
+// by copying its whole route table. Each name is looked up when the route is first matched, so routes
+// registered while serving are picked up. Echo instances are expected to live as long as the process.
+var routeNames sync.Map
+
+// routeName returns the name echo recorded for the route of method and path, or "" when there is none
+func routeName(c echo.Context, method, path string) string {
+	key := routeKey{echo: c.Echo(), method: method, path: path}
+	if name, ok := routeNames.Load(key); ok {
+		return name.(string)
+	}
+
+	name := ""
+	for _, r := range key.echo.Routes() {
+		if r.Method == method && r.Path == path {
+			name = r.Name
+			break
+		}
+	}
+	routeNames.Store(key, name)
+	return name
+}
+
+// operation returns the method and route template, or only the method when no route matched
+func operation(method, route string) string {
+	if route == "" {
+		return method
+	}
+	return method + " " + route
+}
+
+// isCompressed reports whether a Content-Encoding header lists a compression coding
+func isCompressed(encoding string) bool {
+	for _, coding := range strings.Split(encoding, ",") {
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "br", "deflate":
+			return true
+		}
+	}
+	return false
+}
+
+// budgetPercent returns latency as a percentage of budget, capped at 100
+func budgetPercent(latency, budget time.Duration) float64 {
+	if budget <= 0 || latency >= budget {
+		return 100
+	}
+	return float64(latency) / float64(budget) * 100
+}
+
+// countFields returns the number of fields, ignoring namespace markers and skipped fields
+func countFields(fields []zapcore.Field) int {
+	n := 0
+	for _, field := range fields {
+		if field.Type != zapcore.NamespaceType && field.Type != zapcore.SkipType {
+			n++
+		}
+	}
+	return n
+}
+
+// milliseconds returns d as fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// addServerTiming adds a Server-Timing metric with the total duration in milliseconds
+func addServerTiming(header http.Header, d time.Duration) {
+	header.Add("Server-Timing", "total;dur="+strconv.FormatFloat(milliseconds(d), 'f', 3, 64))
+}
+
+// rawHostHeader returns the Host header the request was sent with, or "" when it can't be recovered.
+// The HTTP/1 server removes it from the headers once it set the request host from it, which an
+// absolute-form request-URI takes precedence over. HTTP/2 sets the host from :authority and keeps a Host
+// header sent besides it.
+func rawHostHeader(req *http.Request) string {
+	if host := req.Header.Get("Host"); host != "" {
+		return host
+	}
+	if req.ProtoMajor == 1 && req.URL.Host == "" {
+		return req.Host
+	}
+	return ""
+}
+
+// isSameHost reports whether rawURL points to host
+func isSameHost(rawURL, host string) bool {
+	if rawURL == "" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+// isCrossSite reports whether the Origin, or else the Referer, of req points to another host, and
+// whether either header is set. An opaque "null" origin is cross-site.
+func isCrossSite(req *http.Request) (bool, bool) {
+	source := req.Header.Get(echo.HeaderOrigin)
+	if source == "" {
+		source = req.Referer()
+	}
+	if source == "" {
+		return false, false
+	}
+	return !isSameHost(source, req.Host), true
+}
+
+// headerInt returns the integer value of a header
+func headerInt(header http.Header, name string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(header.Get(name)))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// headerBool returns the boolean value of a header
+func headerBool(header http.Header, name string) (bool, bool) {
+	b, err := strconv.ParseBool(strings.TrimSpace(header.Get(name)))
+	if err != nil {
+		return false, false
+	}
+	return b, true
+}
+
+// headerTime parses a header holding either an HTTP date or a Unix timestamp, optionally prefixed with
+// "t=" as in X-Request-Start. Integer timestamps are read as seconds, milliseconds or microseconds by magnitude.
+func headerTime(header http.Header, name string) (time.Time, bool) {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return t, true
+	}
+
+	value = strings.TrimPrefix(value, "t=")
+	if !strings.Contains(value, ".") {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return time.Time{}, false
+		}
+		switch {
+		case n >= 1e15:
+			return time.Unix(0, n*int64(time.Microsecond)), true
+		case n >= 1e12:
+			return time.Unix(0, n*int64(time.Millisecond)), true
+		default:
+			return time.Unix(n, 0), true
+		}
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// totalAlloc returns the cumulative bytes allocated by the process
+func totalAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.TotalAlloc
+}
+
+// headerSize returns the number of header values and the total size of their names and values
+func headerSize(header http.Header) (count, size int) {
+	for name, values := range header {
+		for _, value := range values {
+			count++
+			size += len(name) + len(value)
+		}
+	}
+	return count, size
+}
+
+// stripPort returns the host part of a host:port address, or addr itself when it has no port
+func stripPort(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// requestID returns the request id generated by the middleware, or else the one from the request header,
+// or the response one when missing
+func requestID(c echo.Context) string {
+	if id, ok := c.Get(requestIDKey).(string); ok {
+		return id
+	}
+	if id := c.Request().Header.Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
 // getLoggerFromContext returns the logger from the context
 func getLoggerFromContext(c echo.Context, loggerKey string) *zap.Logger {
 	contextData := c.Get(loggerKey)
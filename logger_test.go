@@ -1,13 +1,25 @@
 package echozap
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 )
 
@@ -25,7 +37,7 @@ func TestZapLogger(t *testing.T) {
 
 	logger := zap.New(obs)
 
-	err := ZapLogger(logger)(h)(c)
+	err := ZapLogger(&Options{Logger: logger})(h)(c)
 
 	assert.Nil(t, err)
 
@@ -38,3 +50,2055 @@ func TestZapLogger(t *testing.T) {
 	assert.NotNil(t, logFields["host"])
 	assert.NotNil(t, logFields["size"])
 }
+
+func TestZapLoggerLevelRules(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusUnprocessableEntity, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	logger := zap.New(obs)
+
+	err := ZapLogger(&Options{
+		Logger: logger,
+		LevelRules: []LevelRule{
+			{Range: StatusRange{Min: 422, Max: 422}, Level: zapcore.InfoLevel},
+		},
+	})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, zapcore.InfoLevel, logs.All()[0].Level)
+	assert.Equal(t, "Client: Unprocessable Entity", logs.All()[0].Message)
+}
+
+func TestZapLoggerLevelResolver(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger: zap.New(obs),
+		LevelResolver: func(c echo.Context, err error) zapcore.Level {
+			switch status := c.Response().Status; {
+			case status == http.StatusNotFound:
+				return zapcore.InfoLevel
+			case status == 499 || err == context.Canceled:
+				return zapcore.DebugLevel
+			default:
+				return defaultLevel(status)
+			}
+		},
+	}))
+	e.GET("/canceled", func(c echo.Context) error {
+		c.Response().WriteHeader(499)
+		return context.Canceled
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return c.String(http.StatusInternalServerError, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/canceled", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	assert.Equal(t, 3, logs.Len())
+	assert.Equal(t, zapcore.InfoLevel, logs.All()[0].Level)
+	assert.Equal(t, zapcore.DebugLevel, logs.All()[1].Level)
+	assert.Equal(t, zapcore.ErrorLevel, logs.All()[2].Level)
+}
+
+func TestZapLoggerOperation(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogOperation: true}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "GET /users/:id", logs.All()[0].ContextMap()["operation"])
+	assert.Equal(t, "GET /users/42", logs.All()[0].ContextMap()["request"])
+	assert.Equal(t, "GET", logs.All()[1].ContextMap()["operation"])
+}
+
+func TestZapLoggerStatusText(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusNotFound, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogStatusText: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Not Found", logs.All()[0].ContextMap()["status_text"])
+}
+
+func TestZapLoggerUnknownStatus(t *testing.T) {
+	h := func(c echo.Context) error {
+		return nil
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/something", nil), httptest.NewRecorder())
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, zapcore.WarnLevel, logs.All()[0].Level)
+		assert.Equal(t, "Unknown status", logs.All()[0].Message)
+		assert.Equal(t, int64(0), logs.All()[0].ContextMap()["status"])
+	})
+
+	t.Run("normalize", func(t *testing.T) {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/something", nil), httptest.NewRecorder())
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), NormalizeUnknownStatus: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, zapcore.InfoLevel, logs.All()[0].Level)
+		assert.Equal(t, "Success: OK", logs.All()[0].Message)
+		assert.Equal(t, int64(200), logs.All()[0].ContextMap()["status"])
+		assert.Equal(t, true, logs.All()[0].ContextMap()["status_unknown"])
+	})
+}
+
+func TestZapLoggerEntryMarshaler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		EntryMarshaler: func(c echo.Context) zapcore.ObjectMarshaler {
+			return zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("path", c.Request().URL.Path)
+				return enc.AddObject("response", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+					enc.AddInt("status", c.Response().Status)
+					return nil
+				}))
+			})
+		},
+	})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, map[string]interface{}{
+		"path":     "/something",
+		"response": map[string]interface{}{"status": 200},
+	}, logFields["http"])
+	assert.NotContains(t, logFields, "status")
+}
+
+func TestZapLoggerHosts(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogEffectiveHost: true, LogRawHostHeader: true}))
+	e.GET("/something", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	// requests as read off the wire by the HTTP/1 server, which removes the Host header
+	for _, raw := range []string{
+		"GET /something HTTP/1.1\r\nHost: legacy.example.com\r\n\r\n",
+		"GET http://api.example.com/something HTTP/1.1\r\nHost: legacy.example.com\r\n\r\n",
+	} {
+		req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+		assert.Nil(t, err)
+		assert.Empty(t, req.Header.Get("Host"))
+		req.RemoteAddr = "192.0.2.1:1234"
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// a request as built by the HTTP/2 server, the host coming from :authority and a Host header sent
+	// besides it being kept
+	for _, header := range []http.Header{{"Host": {"legacy.example.com"}}, {}} {
+		req := &http.Request{
+			Method:     http.MethodGet,
+			URL:        &url.URL{Path: "/something"},
+			Proto:      "HTTP/2.0",
+			ProtoMajor: 2,
+			Header:     header,
+			Host:       "api.example.com",
+			RequestURI: "/something",
+			RemoteAddr: "192.0.2.1:1234",
+		}
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	entries := logs.All()
+	assert.Equal(t, 4, len(entries))
+	for i, hosts := range [][2]string{
+		{"legacy.example.com", "legacy.example.com"},
+		{"api.example.com", ""},
+		{"api.example.com", "legacy.example.com"},
+		{"api.example.com", ""},
+	} {
+		logFields := entries[i].ContextMap()
+		assert.Equal(t, hosts[0], logFields["effective_host"], i)
+		if hosts[1] == "" {
+			assert.NotContains(t, logFields, "raw_host_header", i)
+		} else {
+			assert.Equal(t, hosts[1], logFields["raw_host_header"], i)
+		}
+	}
+}
+
+func TestZapLoggerCompressed(t *testing.T) {
+	tests := map[string]bool{
+		"gzip": true,
+		"":     false,
+	}
+
+	for encoding, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			if encoding != "" {
+				c.Response().Header().Set(echo.HeaderContentEncoding, encoding)
+			}
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogCompressed: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["compressed"], encoding)
+	}
+}
+
+func TestZapLoggerCacheControl(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "max-age=3600")
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogCacheControl: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "max-age=3600", logs.All()[0].ContextMap()["cache_control"])
+}
+
+func TestZapLoggerETag(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Response().Header().Set("ETag", `W/"5e15153d-120f"`)
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogETag: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `W/"5e15153d-120f"`, logs.All()[0].ContextMap()["etag"])
+}
+
+func TestZapLoggerRemoteAddr(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.RemoteAddr = "10.0.0.7:51234"
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogRemoteAddr: true})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "203.0.113.9", logFields["remote_ip"])
+	assert.Equal(t, "10.0.0.7", logFields["remote_addr"])
+}
+
+func TestZapLoggerServerTiming(t *testing.T) {
+	handlers := map[string]echo.HandlerFunc{
+		"uncommitted": func(c echo.Context) error {
+			return nil
+		},
+		"committed": func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		},
+	}
+
+	for name, h := range handlers {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := ZapLogger(&Options{Logger: zap.NewNop(), EmitServerTiming: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Regexp(t, `^total;dur=\d+\.\d{3}$`, c.Response().Header().Get("Server-Timing"), name)
+	}
+}
+
+func TestZapLoggerCustomFieldsNamespace(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Set(DefaultCustomFieldsKey, []zapcore.Field{zap.String("user", "bob"), zap.Int("items", 3)})
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), CustomFieldsNamespace: "app"})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, int64(200), logFields["status"])
+	assert.NotContains(t, logFields, "user")
+	assert.Equal(t, map[string]interface{}{"user": "bob", "items": int64(3)}, logFields["app"])
+}
+
+func TestZapLoggerCustomFieldsNamespaceTopLevel(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.Header.Set("User-Agent", strings.Repeat("a", 200))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Set(DefaultCustomFieldsKey, []zapcore.Field{zap.String("k", "v")})
+		return errors.New("boom")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), CustomFieldsNamespace: "app", MaxEntryBytes: 300})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "boom", logFields["error"])
+	assert.Equal(t, true, logFields["truncated"])
+	assert.Equal(t, map[string]interface{}{"k": "v"}, logFields["app"])
+}
+
+func TestZapLoggerStatusClass(t *testing.T) {
+	tests := map[int]int64{
+		http.StatusNotFound:  4,
+		http.StatusNoContent: 2,
+	}
+
+	for status, class := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.NoContent(status)
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogStatusClass: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, class, logs.All()[0].ContextMap()["status_class"])
+	}
+}
+
+func TestZapLoggerTimestamps(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		time.Sleep(2 * time.Millisecond)
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogTimestamps: true})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	received, err := time.Parse(time.RFC3339Nano, logFields["received_at"].(string))
+	assert.Nil(t, err)
+	completed, err := time.Parse(time.RFC3339Nano, logFields["completed_at"].(string))
+	assert.Nil(t, err)
+	latency, err := time.ParseDuration(logFields["latency"].(string))
+	assert.Nil(t, err)
+
+	assert.False(t, completed.Before(received))
+	assert.InDelta(t, float64(latency), float64(completed.Sub(received)), float64(time.Millisecond))
+}
+
+func TestZapLoggerNumericLatency(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:          zap.New(obs),
+		NumericLatency:  true,
+		LogLatencyMs:    true,
+		LogStartEndTime: true,
+		Clock:           stepClock(start, 1500*time.Microsecond),
+	})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, 1500*time.Microsecond, logFields["latency"])
+	assert.Equal(t, 1.5, logFields["latency_ms"])
+	assert.Equal(t, start, logFields["start_time"])
+	assert.Equal(t, start.Add(1500*time.Microsecond), logFields["end_time"])
+}
+
+func TestZapLoggerErrorHandled(t *testing.T) {
+	handlers := map[bool]echo.HandlerFunc{
+		true: func(c echo.Context) error {
+			return errors.New("boom")
+		},
+		false: func(c echo.Context) error {
+			return c.String(http.StatusInternalServerError, "boom")
+		},
+	}
+
+	for expected, h := range handlers {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogErrorHandled: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(500), logs.All()[0].ContextMap()["status"])
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["error_handled_by_middleware"])
+	}
+}
+
+// stepClock returns a clock starting at start and moving forward by step on every call after the first
+func stepClock(start time.Time, step time.Duration) func() time.Time {
+	now := start.Add(-step)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestZapLoggerLatencyBudget(t *testing.T) {
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		deadline time.Duration
+		expected float64
+	}{
+		{deadline: 200 * time.Millisecond, expected: 25},
+		{deadline: 20 * time.Millisecond, expected: 100},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(test.deadline))
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:           zap.New(obs),
+			LogLatencyBudget: true,
+			Clock:            stepClock(start, 50*time.Millisecond),
+		})(h)(c)
+		cancel()
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, logs.All()[0].ContextMap()["latency_budget_pct"])
+	}
+}
+
+func TestZapLoggerLatencyBudgetWithoutDeadline(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogLatencyBudget: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.NotContains(t, logs.All()[0].ContextMap(), "latency_budget_pct")
+}
+
+func TestZapLoggerTimeout(t *testing.T) {
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// the deadline is set by a timeout middleware running after the logger
+	h := func(c echo.Context) error {
+		ctx, cancel := context.WithDeadline(c.Request().Context(), start.Add(1500*time.Millisecond))
+		defer cancel()
+		c.SetRequest(c.Request().WithContext(ctx))
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:     zap.New(obs),
+		LogTimeout: true,
+		Clock:      stepClock(start, 3*time.Second),
+	})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1500.0, logs.All()[0].ContextMap()["timeout_ms"])
+}
+
+func TestZapLoggerTimeoutWithoutDeadline(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogTimeout: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.NotContains(t, logs.All()[0].ContextMap(), "timeout_ms")
+}
+
+func TestZapLoggerRequestKind(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/something", strings.NewReader("{}"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogRequestKind: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, RequestKindJSON, logs.All()[0].ContextMap()["request_kind"])
+}
+
+func TestZapLoggerErrorChain(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return &wrappedError{msg: "handler", cause: &wrappedError{msg: "service", cause: errors.New("db down")}}
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogErrorChain: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		"handler: service: db down",
+		"service: db down",
+		"db down",
+	}, logs.All()[0].ContextMap()["error_chain"])
+}
+
+func TestZapLoggerAuthScheme(t *testing.T) {
+	for _, authorization := range []string{"Bearer secret-token", "Basic c2VjcmV0"} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogAuthScheme: true})(h)(c)
+
+		assert.Nil(t, err)
+
+		logFields := logs.All()[0].ContextMap()
+		assert.Equal(t, strings.Fields(authorization)[0], logFields["auth_scheme"])
+		for _, value := range logFields {
+			assert.NotContains(t, fmt.Sprint(value), strings.Fields(authorization)[1])
+		}
+	}
+}
+
+func TestZapLoggerPathHash(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogPathHash: true, PathHashAlgorithm: HashSHA256}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	for _, target := range []string{"/users/1", "/users/1?page=2", "/users/2"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	entries := logs.All()
+	assert.Equal(t, hashString(HashSHA256, "/users/1"), entries[0].ContextMap()["path_hash"])
+	assert.Equal(t, entries[0].ContextMap()["path_hash"], entries[1].ContextMap()["path_hash"])
+	assert.NotEqual(t, entries[0].ContextMap()["path_hash"], entries[2].ContextMap()["path_hash"])
+
+	assert.Equal(t, "GET "+hashString(HashSHA256, "/users/1"), entries[0].ContextMap()["request"])
+	assert.Equal(t, "GET "+hashString(HashSHA256, "/users/1")+"?page=2", entries[1].ContextMap()["request"])
+	for _, entry := range entries {
+		for _, field := range entry.Context {
+			assert.NotContains(t, field.String, "/users/", field.Key)
+		}
+	}
+}
+
+func TestZapLoggerPathHashECS(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), Preset: PresetECS, LogPathHash: true})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, hashString(HashFNV, "/users/1"), logFields["url.path"])
+	assert.Equal(t, hashString(HashFNV, "/users/1"), logFields["url.original"])
+}
+
+// slowReader returns one byte per Read after sleeping for delay
+type slowReader struct {
+	data  []byte
+	delay time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
+
+func TestZapLoggerBodyReadTime(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/something", &slowReader{data: []byte("abc"), delay: 10 * time.Millisecond})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogBodyReadTime: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", rec.Body.String())
+	assert.True(t, logs.All()[0].ContextMap()["body_read_ms"].(float64) >= 30)
+}
+
+func TestZapLoggerUnreadBody(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/something", strings.NewReader("0123456789"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		if _, err := io.ReadFull(c.Request().Body, make([]byte, 4)); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogUnreadBody: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), logs.All()[0].ContextMap()["unread_body_bytes"])
+}
+
+func TestZapLoggerInternalError(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError, "something went wrong").SetInternal(errors.New("pq: connection reset"))
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogInternalError: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "pq: connection reset", logs.All()[0].ContextMap()["internal_error"])
+}
+
+func TestZapLoggerFieldCount(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Set(DefaultCustomFieldsKey, []zapcore.Field{zap.String("a", "1"), zap.String("b", "2"), zap.String("c", "3")})
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogFieldCount: true, CustomFieldsNamespace: "app"})(h)(c)
+
+	assert.Nil(t, err)
+
+	// 7 standard fields, request_id and 3 custom fields
+	assert.Equal(t, int64(11), logs.All()[0].ContextMap()["field_count"])
+}
+
+func TestZapLoggerConditional(t *testing.T) {
+	tests := map[string]bool{
+		"If-None-Match":     true,
+		"If-Modified-Since": true,
+		"":                  false,
+	}
+
+	for header, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		if header != "" {
+			req.Header.Set(header, `"v1"`)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogConditional: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["if_none_match"], header)
+	}
+}
+
+func TestZapLoggerGRPCStatus(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Response().Header().Set("Grpc-Status", "5")
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogGRPCStatus: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), logs.All()[0].ContextMap()["grpc_status"])
+}
+
+func TestZapLoggerDeadLinks(t *testing.T) {
+	tests := []struct {
+		referer  string
+		deadLink interface{}
+		level    zapcore.Level
+	}{
+		{referer: "http://example.com/blog", deadLink: true, level: zapcore.WarnLevel},
+		{referer: "https://other.com/", deadLink: nil, level: zapcore.InfoLevel},
+		{referer: "", deadLink: nil, level: zapcore.InfoLevel},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/old-page", nil)
+		if test.referer != "" {
+			req.Header.Set("Referer", test.referer)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), DetectDeadLinks: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.level, logs.All()[0].Level, test.referer)
+		assert.Equal(t, test.deadLink, logs.All()[0].ContextMap()["dead_link"], test.referer)
+	}
+}
+
+func TestZapLoggerRedirectCount(t *testing.T) {
+	tests := map[string]interface{}{
+		"3":    int64(3),
+		"many": nil,
+		"":     nil,
+	}
+
+	for value, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		if value != "" {
+			req.Header.Set("X-Redirect-Count", value)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), RedirectCountHeader: "X-Redirect-Count"})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["redirect_count"], value)
+	}
+}
+
+func TestZapLoggerLocale(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.Header.Set("Accept-Language", "en;q=0.7, pt-BR, fr;q=0.9")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogLocale: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "pt-BR", logs.All()[0].ContextMap()["locale"])
+}
+
+// panicCore is a core whose writes panic
+type panicCore struct {
+	zapcore.LevelEnabler
+}
+
+func (p panicCore) With([]zapcore.Field) zapcore.Core { return p }
+func (p panicCore) Sync() error                       { return nil }
+func (p panicCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, p)
+}
+func (p panicCore) Write(zapcore.Entry, []zapcore.Field) error {
+	panic("broken encoder")
+}
+
+func TestZapLoggerFailOpen(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	var err error
+	assert.NotPanics(t, func() {
+		err = ZapLogger(&Options{Logger: zap.New(panicCore{zapcore.DebugLevel}), FallbackLogger: zap.New(obs)})(h)(c)
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "broken encoder", logs.All()[0].ContextMap()["panic"])
+}
+
+func TestZapLoggerFailClosed(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello")
+	}
+
+	assert.PanicsWithValue(t, "broken encoder", func() {
+		ZapLogger(&Options{Logger: zap.New(panicCore{zapcore.DebugLevel}), FailClosed: true})(h)(c)
+	})
+}
+
+func TestZapLoggerResolver(t *testing.T) {
+	defaultObs, defaultLogs := observer.New(zap.DebugLevel)
+	acmeObs, acmeLogs := observer.New(zap.DebugLevel)
+	acme := zap.New(acmeObs)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger: zap.New(defaultObs),
+		LoggerResolver: func(c echo.Context) *zap.Logger {
+			if c.Request().Header.Get("X-Tenant") == "acme" {
+				return acme
+			}
+			return nil
+		},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if i%2 == 0 {
+				req.Header.Set("X-Tenant", "acme")
+			}
+			e.ServeHTTP(httptest.NewRecorder(), req)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, acmeLogs.Len())
+	assert.Equal(t, 10, defaultLogs.Len())
+}
+
+func TestZapLoggerResolverScoped(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	acme := zap.New(obs)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:             zap.NewNop(),
+		InjectScopedLogger: true,
+		LogCorrelationID:   true,
+		LogRoute:           true,
+		LoggerResolver: func(c echo.Context) *zap.Logger {
+			return acme
+		},
+	}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, http.MethodGet, fields["method"])
+	assert.Equal(t, "/users/:id", fields["route"])
+	assert.Equal(t, "req-1", fields["correlation_id"])
+}
+
+func TestZapLoggerMutation(t *testing.T) {
+	for method, expected := range map[string]bool{http.MethodPatch: true, http.MethodGet: false} {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogMutation: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["is_mutation"], method)
+	}
+}
+
+func TestZapLoggerRetryAttempt(t *testing.T) {
+	tests := map[string]interface{}{
+		"2": int64(2),
+		"0": nil,
+		"":  nil,
+	}
+
+	for value, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		if value != "" {
+			req.Header.Set("X-Retry-Attempt", value)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), RetryAttemptHeader: "X-Retry-Attempt"})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["retry_attempt"], value)
+	}
+}
+
+func TestZapLoggerOmitLatencyBelow(t *testing.T) {
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	tests := map[time.Duration]bool{
+		500 * time.Microsecond: false,
+		2 * time.Millisecond:   true,
+	}
+
+	for latency, logged := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/static.css", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:           zap.New(obs),
+			OmitLatencyBelow: time.Millisecond,
+			Clock:            stepClock(start, latency),
+			LogFieldCount:    true,
+			LogLatencyMs:     true,
+		})(h)(c)
+
+		assert.Nil(t, err)
+
+		logFields := logs.All()[0].ContextMap()
+		_, ok := logFields["latency"]
+		assert.Equal(t, logged, ok, latency.String())
+		_, ok = logFields["latency_ms"]
+		assert.Equal(t, logged, ok, latency.String())
+
+		c = e.NewContext(httptest.NewRequest(http.MethodGet, "/static.css", nil), httptest.NewRecorder())
+		err = ZapLogger(&Options{
+			Logger:           zap.New(obs),
+			OmitLatencyBelow: time.Millisecond,
+			Clock:            stepClock(start, latency),
+			Values:           &RequestLoggerValues{LogLatency: true, LogStatus: true},
+		})(h)(c)
+
+		assert.Nil(t, err)
+
+		_, ok = logs.All()[1].ContextMap()["latency"]
+		assert.Equal(t, logged, ok, latency.String())
+	}
+}
+
+func TestZapLoggerDeviceType(t *testing.T) {
+	classifiers := map[string]func(string) string{
+		DeviceMobile: nil,
+		"watch": func(ua string) string {
+			return "watch"
+		},
+	}
+
+	for expected, classifier := range classifiers {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 13_2 like Mac OS X) Mobile/15E148")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogDeviceType: true, DeviceClassifier: classifier})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["device_type"])
+	}
+}
+
+func TestZapLoggerSlowDiagnostics(t *testing.T) {
+	steps := map[time.Duration]bool{
+		50 * time.Millisecond:  false,
+		500 * time.Millisecond: true,
+	}
+
+	for step, present := range steps {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:               zap.New(obs),
+			Clock:                stepClock(time.Now(), step),
+			SlowRequestThreshold: 100 * time.Millisecond,
+			LogSlowDiagnostics:   true,
+		})(h)(c)
+
+		assert.Nil(t, err)
+		goroutines, ok := logs.All()[0].ContextMap()["num_goroutines"]
+		assert.Equal(t, present, ok, step.String())
+		if present {
+			assert.True(t, goroutines.(int64) > 0)
+		}
+	}
+}
+
+func TestZapLoggerSlowRequest(t *testing.T) {
+	handlers := map[int]zapcore.Level{http.StatusOK: zapcore.WarnLevel, http.StatusInternalServerError: zapcore.ErrorLevel}
+
+	for status, level := range handlers {
+		for _, step := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond} {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/something", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := func(c echo.Context) error {
+				return c.String(status, "")
+			}
+
+			obs, logs := observer.New(zap.DebugLevel)
+
+			err := ZapLogger(&Options{
+				Logger:               zap.New(obs),
+				Clock:                stepClock(time.Now(), step),
+				SlowRequestThreshold: 100 * time.Millisecond,
+			})(h)(c)
+
+			assert.Nil(t, err)
+			entry := logs.All()[0]
+			if step < 100*time.Millisecond {
+				assert.NotContains(t, entry.ContextMap(), "slow_request")
+				continue
+			}
+			assert.Equal(t, level, entry.Level)
+			assert.Equal(t, true, entry.ContextMap()["slow_request"])
+			assert.Equal(t, 100*time.Millisecond, entry.ContextMap()["slow_threshold"])
+		}
+	}
+}
+
+func TestZapLoggerAllocBytes(t *testing.T) {
+	tests := []struct {
+		options Options
+		present bool
+	}{
+		{options: Options{LogAllocBytes: true}, present: true},
+		{options: Options{LogAllocBytes: true, SlowRequestThreshold: time.Second}, present: false},
+		{options: Options{LogAllocBytes: true, SlowRequestThreshold: time.Millisecond}, present: true},
+		{options: Options{}, present: false},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, strings.Repeat("x", 64<<10))
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		options := test.options
+		options.Logger = zap.New(obs)
+		options.Clock = stepClock(time.Now(), 10*time.Millisecond)
+		err := ZapLogger(&options)(h)(c)
+
+		assert.Nil(t, err)
+		alloc, ok := logs.All()[0].ContextMap()["alloc_bytes"]
+		assert.Equal(t, test.present, ok)
+		if test.present {
+			assert.True(t, alloc.(uint64) > 0)
+		}
+	}
+}
+
+func TestZapLoggerClockSkew(t *testing.T) {
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		value  string
+		skew   interface{}
+	}{
+		{"Date", start.Add(2 * time.Second).Format(http.TimeFormat), 2000.0},
+		{"Date", start.Add(-3 * time.Second).Format(http.TimeFormat), -3000.0},
+		{"X-Request-Start", "t=" + strconv.FormatInt(start.Add(-250*time.Millisecond).UnixNano()/int64(time.Millisecond), 10), -250.0},
+		{"X-Request-Start", "t=" + strconv.FormatInt(start.Add(1500*time.Microsecond).UnixNano()/int64(time.Microsecond), 10), 1.5},
+		{"X-Request-Start", "t=1574330399.5", -500.0},
+		{"X-Request-Start", "yesterday", nil},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		req.Header.Set(test.header, test.value)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:          zap.New(obs),
+			Clock:           stepClock(start, time.Millisecond),
+			ClockSkewHeader: test.header,
+		})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.skew, logs.All()[0].ContextMap()["clock_skew_ms"], test.value)
+	}
+}
+
+func TestZapLoggerLargeHeaders(t *testing.T) {
+	tests := []struct {
+		options Options
+		header  int
+		large   bool
+	}{
+		{options: Options{MaxHeaderCount: 10}, header: 11, large: true},
+		{options: Options{MaxHeaderCount: 10}, header: 10, large: false},
+		{options: Options{MaxHeaderBytes: 100}, header: 6, large: true},
+		{options: Options{MaxHeaderBytes: 100}, header: 5, large: false},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		// each header is 20 bytes
+		for i := 0; i < test.header; i++ {
+			req.Header.Set(fmt.Sprintf("X-Header-%02d", i), "justbytes")
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		options := test.options
+		options.Logger = zap.New(obs)
+		err := ZapLogger(&options)(h)(c)
+
+		assert.Nil(t, err)
+
+		entry := logs.All()[0]
+		if test.large {
+			assert.Equal(t, true, entry.ContextMap()["large_headers"])
+			assert.Equal(t, zapcore.WarnLevel, entry.Level)
+		} else {
+			assert.NotContains(t, entry.ContextMap(), "large_headers")
+			assert.Equal(t, zapcore.InfoLevel, entry.Level)
+		}
+	}
+}
+
+func TestZapLoggerResponseFormat(t *testing.T) {
+	handlers := map[string]echo.HandlerFunc{
+		ResponseFormatJSON: func(c echo.Context) error {
+			return c.JSON(http.StatusOK, map[string]string{"ok": "yes"})
+		},
+		ResponseFormatXML: func(c echo.Context) error {
+			return c.XML(http.StatusOK, struct{}{})
+		},
+		ResponseFormatHTML: func(c echo.Context) error {
+			return c.HTML(http.StatusOK, "<p>ok</p>")
+		},
+		ResponseFormatText: func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		},
+		ResponseFormatBinary: func(c echo.Context) error {
+			return c.Blob(http.StatusOK, echo.MIMEOctetStream, []byte{0x1})
+		},
+	}
+
+	for expected, h := range handlers {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogResponseFormat: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["response_format"])
+	}
+}
+
+func TestZapLoggerTLSServerName(t *testing.T) {
+	tests := map[string]interface{}{
+		"https://api.example.com/something": "api.example.com",
+		"http://api.example.com/something":  nil,
+	}
+
+	for target, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if req.TLS != nil {
+			req.TLS.ServerName = "api.example.com"
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogTLSServerName: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["tls_server_name"], target)
+	}
+}
+
+func TestZapLoggerCrossSite(t *testing.T) {
+	tests := []struct {
+		origin   string
+		referer  string
+		omit     bool
+		expected interface{}
+	}{
+		{origin: "https://example.com", expected: false},
+		{origin: "https://evil.test", expected: true},
+		{origin: "null", expected: true},
+		{referer: "https://evil.test/page", expected: true},
+		{referer: "https://example.com/page", expected: false},
+		{expected: false},
+		{omit: true, expected: nil},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/transfer", nil)
+		if test.origin != "" {
+			req.Header.Set(echo.HeaderOrigin, test.origin)
+		}
+		if test.referer != "" {
+			req.Header.Set("Referer", test.referer)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogCrossSite: true, OmitUnknownCrossSite: test.omit})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, logs.All()[0].ContextMap()["cross_site"], test.origin+test.referer)
+	}
+}
+
+// listUsers is a named handler for TestZapLoggerHandlerFunc
+func listUsers(c echo.Context) error {
+	return c.String(http.StatusOK, "")
+}
+
+func TestZapLoggerHandlerFunc(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogHandlerFunc: true}))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return next(c)
+		}
+	})
+	e.GET("/users", listUsers)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, "github.com/dhillondeep/echozap.listUsers", logs.All()[0].ContextMap()["handler_func"])
+	assert.NotContains(t, logs.All()[1].ContextMap(), "handler_func")
+}
+
+func TestRouteName(t *testing.T) {
+	e := echo.New()
+	e.GET("/users", listUsers)
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/users", nil), httptest.NewRecorder())
+
+	assert.Equal(t, "github.com/dhillondeep/echozap.listUsers", routeName(c, http.MethodGet, "/users"))
+	assert.Equal(t, "github.com/dhillondeep/echozap.listUsers", routeName(c, http.MethodGet, "/users"))
+	assert.Equal(t, "", routeName(c, http.MethodPost, "/users"))
+
+	e.PUT("/users", listUsers)
+	assert.Equal(t, "github.com/dhillondeep/echozap.listUsers", routeName(c, http.MethodPut, "/users"))
+	assert.Equal(t, "", routeName(echo.New().NewContext(nil, nil), http.MethodGet, "/users"))
+}
+
+func TestZapLoggerRoute(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogRoute: true, LogPathParams: true}))
+	e.GET("/users/:id/orders/:order", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/users", listUsers)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42/orders/7", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "/users/:id/orders/:order", logFields["route"])
+	assert.Equal(t, map[string]interface{}{"id": "42", "order": "7"}, logFields["path_params"])
+
+	assert.Equal(t, "/users", logs.All()[1].ContextMap()["route"])
+	assert.NotContains(t, logs.All()[1].ContextMap(), "path_params")
+
+	assert.NotContains(t, logs.All()[2].ContextMap(), "route")
+}
+
+func TestZapLoggerSuppressClientStacktraces(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:                    zap.New(obs, zap.AddStacktrace(zapcore.WarnLevel)),
+		SuppressClientStacktraces: true,
+	}))
+	e.GET("/bad", func(c echo.Context) error {
+		return c.String(http.StatusBadRequest, "")
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return c.String(http.StatusInternalServerError, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bad", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	assert.Equal(t, zapcore.WarnLevel, logs.All()[0].Level)
+	assert.Empty(t, logs.All()[0].Stack)
+	assert.Equal(t, zapcore.ErrorLevel, logs.All()[1].Level)
+	assert.NotEmpty(t, logs.All()[1].Stack)
+}
+
+func TestZapLoggerPathParamsRecycledContext(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogPathParams: true, DebugQueryParam: "debug"}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1?debug=1", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2?debug=1", nil))
+
+	for i, id := range []string{"1", "2"} {
+		logFields := logs.All()[i].ContextMap()
+		assert.Equal(t, map[string]interface{}{"id": id}, logFields["path_params"])
+		assert.Equal(t, map[string]interface{}{"id": id}, logFields["params"])
+	}
+}
+
+func TestZapLoggerRouteMatched(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogRouteMatched: true}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusNotFound, "no such user")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unregistered", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	for i, matched := range []bool{false, true} {
+		logFields := logs.All()[i].ContextMap()
+		assert.Equal(t, int64(http.StatusNotFound), logFields["status"])
+		assert.Equal(t, matched, logFields["route_matched"])
+	}
+}
+
+func TestZapLoggerRouteMatchedGroup(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogRouteMatched: true, LogOperation: true, LogRoute: true}))
+	v1 := e.Group("/api/v1", RouteGroup("/api/v1"))
+	v1.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusNotFound, "no such user")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/unregistered", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil))
+
+	for i, matched := range []bool{false, false, true} {
+		logFields := logs.All()[i].ContextMap()
+		assert.Equal(t, int64(http.StatusNotFound), logFields["status"])
+		assert.Equal(t, matched, logFields["route_matched"])
+		if matched {
+			assert.Equal(t, "/api/v1/users/:id", logFields["route"])
+			assert.Equal(t, "GET /api/v1/users/:id", logFields["operation"])
+		} else {
+			assert.NotContains(t, logFields, "route")
+			assert.NotContains(t, logFields["operation"], "/api/v1")
+		}
+	}
+}
+
+func TestZapLoggerQueryParamCount(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/search?q=zap&tag=go&tag=log&page=2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogQueryParamCount: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), logs.All()[0].ContextMap()["query_param_count"])
+}
+
+func TestZapLoggerKeepAliveReusedHeader(t *testing.T) {
+	tests := map[string]interface{}{
+		"true":  true,
+		"false": false,
+		"maybe": nil,
+		"":      nil,
+	}
+
+	for value, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		if value != "" {
+			req.Header.Set("X-Connection-Reused", value)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), KeepAliveReusedHeader: "X-Connection-Reused"})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["keepalive_reused"], value)
+	}
+}
+
+func TestZapLoggerLatencyBuckets(t *testing.T) {
+	buckets := &LatencyBuckets{Fast: 100 * time.Millisecond, Acceptable: 500 * time.Millisecond, Slow: 2 * time.Second}
+
+	tests := map[time.Duration]string{
+		50 * time.Millisecond:  LatencyFast,
+		100 * time.Millisecond: LatencyFast,
+		300 * time.Millisecond: LatencyAcceptable,
+		time.Second:            LatencySlow,
+		5 * time.Second:        LatencyCritical,
+	}
+
+	for latency, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:         zap.New(obs),
+			Clock:          stepClock(time.Now(), latency),
+			LatencyBuckets: buckets,
+		})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["latency_bucket"], latency.String())
+	}
+}
+
+func TestZapLoggerRequestIDPrecedence(t *testing.T) {
+	tests := []struct {
+		request  string
+		response string
+		id       string
+		mismatch interface{}
+	}{
+		{request: "abc", response: "abc", id: "abc"},
+		{request: "abc", response: "def", id: "abc", mismatch: true},
+		{request: "abc", id: "abc"},
+		{response: "def", id: "def"},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		if test.request != "" {
+			req.Header.Set(echo.HeaderXRequestID, test.request)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			if test.response != "" {
+				c.Response().Header().Set(echo.HeaderXRequestID, test.response)
+			}
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+
+		assert.Nil(t, err)
+
+		logFields := logs.All()[0].ContextMap()
+		assert.Equal(t, test.id, logFields["request_id"])
+		assert.Equal(t, test.mismatch, logFields["request_id_mismatch"])
+	}
+}
+
+func TestZapLoggerServerUptime(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:          zap.New(obs),
+		Clock:           stepClock(time.Now(), 30*time.Second),
+		LogServerUptime: true,
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 30.0, logs.All()[0].ContextMap()["server_uptime_s"])
+	assert.Equal(t, 90.0, logs.All()[1].ContextMap()["server_uptime_s"])
+}
+
+func TestZapLoggerAPIVersion(t *testing.T) {
+	tests := map[string]interface{}{
+		"application/vnd.myapi.v2+json": "v2",
+		"application/json":              nil,
+	}
+
+	for accept, expected := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogAPIVersion: true})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, logs.All()[0].ContextMap()["api_version"], accept)
+	}
+}
+
+func TestZapLoggerFirstRequest(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogFirstRequest: true}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return c.String(http.StatusInternalServerError, "")
+	})
+
+	// failures don't count as the first request
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+	assert.Equal(t, 0, logs.FilterMessage("First request").Len())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	}
+	wg.Wait()
+
+	first := logs.FilterMessage("First request").All()
+	assert.Len(t, first, 1)
+	assert.Equal(t, zapcore.InfoLevel, first[0].Level)
+	assert.Equal(t, true, first[0].ContextMap()["first_request"])
+	assert.Equal(t, "GET /", first[0].ContextMap()["request"])
+	assert.Equal(t, 22, logs.Len())
+}
+
+func TestZapLoggerSizeMismatch(t *testing.T) {
+	tests := map[string]bool{
+		"10": true,
+		"5":  false,
+		"":   false,
+	}
+
+	for contentLength, mismatch := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			if contentLength != "" {
+				c.Response().Header().Set(echo.HeaderContentLength, contentLength)
+			}
+			return c.String(http.StatusOK, "short")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), LogSizeMismatch: true})(h)(c)
+
+		assert.Nil(t, err)
+
+		entry := logs.All()[0]
+		if mismatch {
+			assert.Equal(t, zapcore.WarnLevel, entry.Level)
+			assert.Equal(t, true, entry.ContextMap()["size_mismatch"])
+			assert.Equal(t, int64(10), entry.ContextMap()["declared_size"])
+			assert.Equal(t, int64(5), entry.ContextMap()["actual_size"])
+		} else {
+			assert.Equal(t, zapcore.InfoLevel, entry.Level, contentLength)
+			assert.NotContains(t, entry.ContextMap(), "size_mismatch")
+		}
+	}
+}
+
+func TestZapLoggerSkipper(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:    zap.New(obs),
+		SkipPaths: []string{"/healthz", "/metrics"},
+		Skipper: func(c echo.Context) bool {
+			return c.Request().Header.Get("X-Probe") != ""
+		},
+	}))
+	handled := 0
+	handler := func(c echo.Context) error {
+		handled++
+		return c.String(http.StatusOK, "")
+	}
+	e.GET("/healthz", handler)
+	e.GET("/metrics", handler)
+	e.GET("/users", handler)
+
+	probe := httptest.NewRequest(http.MethodGet, "/users", nil)
+	probe.Header.Set("X-Probe", "1")
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/healthz", nil),
+		httptest.NewRequest(http.MethodGet, "/metrics", nil),
+		probe,
+		httptest.NewRequest(http.MethodGet, "/users", nil),
+	} {
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 4, handled)
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "GET /users", logs.All()[0].ContextMap()["request"])
+}
+
+func TestZapLoggerBeforeAfter(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Before: func(c echo.Context) {
+			c.Set("tenant", "acme")
+		},
+		After: func(c echo.Context, err error, fields []zapcore.Field) []zapcore.Field {
+			if c.Path() == "/healthz" {
+				return nil
+			}
+
+			kept := fields[:0]
+			for _, field := range fields {
+				if field.Key != "user_agent" {
+					kept = append(kept, field)
+				}
+			}
+			return append(kept, zap.String("tenant", c.Get("tenant").(string)), zap.Bool("failed", err != nil))
+		},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return echo.ErrForbidden
+	})
+	e.GET("/healthz", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, 1, logs.Len())
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "acme", logFields["tenant"])
+	assert.Equal(t, true, logFields["failed"])
+	assert.NotContains(t, logFields, "user_agent")
+	assert.Contains(t, logFields, "status")
+}
+
+func TestZapLoggerClientDisconnect(t *testing.T) {
+	handlers := map[string]echo.HandlerFunc{
+		"context": func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		},
+		"error": func(c echo.Context) error {
+			return context.Canceled
+		},
+	}
+
+	for name, h := range handlers {
+		e := echo.New()
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+		if name == "context" {
+			req = req.WithContext(ctx)
+			cancel()
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{
+			Logger:                 zap.New(obs),
+			DetectClientDisconnect: true,
+			ClientDisconnectLevel:  zapcore.DebugLevel,
+		})(h)(c)
+		cancel()
+
+		assert.Nil(t, err)
+		entry := logs.All()[0]
+		assert.Equal(t, "Client Disconnected", entry.Message, name)
+		assert.Equal(t, zapcore.DebugLevel, entry.Level, name)
+		assert.Equal(t, true, entry.ContextMap()["canceled"], name)
+	}
+}
+
+func TestZapLoggerClientDisconnectDisabled(t *testing.T) {
+	e := echo.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Success: OK", logs.All()[0].Message)
+	assert.NotContains(t, logs.All()[0].ContextMap(), "canceled")
+}
+
+func benchmarkZapLogger(b *testing.B, level zapcore.Level, h echo.HandlerFunc) {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(ioutil.Discard), level)
+	mw := ZapLogger(&Options{Logger: zap.New(core)})(h)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/users/42?page=2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Reset(req, rec)
+		mw(c)
+	}
+}
+
+func BenchmarkZapLoggerSuccess(b *testing.B) {
+	benchmarkZapLogger(b, zapcore.InfoLevel, func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+}
+
+func BenchmarkZapLoggerError(b *testing.B) {
+	benchmarkZapLogger(b, zapcore.InfoLevel, func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError, "boom")
+	})
+}
+
+func BenchmarkZapLoggerDisabled(b *testing.B) {
+	benchmarkZapLogger(b, zapcore.ErrorLevel, func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+}
@@ -0,0 +1,183 @@
+package echozap
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Preset selects the names and layout of the standard access-log fields.
+type Preset int
+
+const (
+	// PresetDefault logs the echozap field names (remote_ip, latency, host, request, status, size, user_agent).
+	PresetDefault Preset = iota
+	// PresetECS logs Elastic Common Schema fields (client.ip, event.duration in nanoseconds,
+	// http.request.method, url.path, http.response.status_code, ...).
+	PresetECS
+	// PresetCloudEvents wraps the entry in a CloudEvents 1.0 envelope (specversion, type, source, id, time)
+	// with the access-log fields, in the default layout, nested under "data". The id is the request id,
+	// or a random one when the request has none.
+	PresetCloudEvents
+	// PresetGCP logs the fields of the Google Cloud Logging structured format: an "httpRequest" object
+	// (requestMethod, requestUrl, status, responseSize, userAgent, remoteIp, latency, ...) and a
+	// "severity" field matching the entry level.
+	PresetGCP
+)
+
+const (
+	// cloudEventsSpecVersion is the CloudEvents specification version of the envelope
+	cloudEventsSpecVersion = "1.0"
+	// CloudEventsType is the CloudEvents type of access-log entries
+	CloudEventsType = "com.github.dhillondeep.echozap.access"
+)
+
+// latencyOmitted reports whether the latency fields of a request are dropped, as per Options.OmitLatencyBelow
+func latencyOmitted(options *Options, latency time.Duration) bool {
+	return latency < options.OmitLatencyBelow
+}
+
+// standardFields appends to dst the standard access-log fields of the request selected by options.Values,
+// or else in the layout of options.Preset
+func standardFields(dst []zapcore.Field, options *Options, c echo.Context, status int, latency time.Duration) []zapcore.Field {
+	req := c.Request()
+	res := c.Response()
+	omitLatency := latencyOmitted(options, latency)
+	ip := remoteIP(options, c)
+
+	if options.Values != nil {
+		return append(dst, options.Values.fields(options, c, ip, status, latency)...)
+	}
+
+	if options.Preset == PresetECS {
+		latencyField := zap.Int64("event.duration", int64(latency))
+		if omitLatency {
+			latencyField = zap.Skip()
+		}
+
+		return append(dst,
+			zap.String("client.ip", ip),
+			latencyField,
+			zap.String("url.domain", req.Host),
+			zap.String("url.path", loggedPath(options, req)),
+			zap.String("url.original", requestTarget(options, req)),
+			zap.String("http.request.method", req.Method),
+			zap.Int("http.response.status_code", status),
+			zap.Int64("http.response.body.bytes", res.Size),
+			zap.String("user_agent.original", req.UserAgent()),
+		)
+	}
+
+	if options.Preset == PresetGCP {
+		return append(dst, zap.Object("httpRequest", gcpHTTPRequest{c: c, remoteIP: ip, target: requestTarget(options, req), status: status, latency: latency, omitLatency: omitLatency}))
+	}
+
+	var latencyField zapcore.Field
+	switch {
+	case omitLatency:
+		latencyField = zap.Skip()
+	case options.NumericLatency:
+		latencyField = zap.Duration("latency", latency)
+	case options.CompactLatency:
+		latencyField = zap.String("latency", formatLatency(latency))
+	default:
+		latencyField = zap.String("latency", latency.String())
+	}
+
+	return append(dst,
+		zap.String("remote_ip", ip),
+		latencyField,
+		zap.String("host", req.Host),
+		zap.String("request", req.Method+" "+requestTarget(options, req)),
+		zap.Int("status", status),
+		zap.Int64("size", res.Size),
+		zap.String("user_agent", req.UserAgent()),
+	)
+}
+
+// cloudEvent wraps fields in a CloudEvents envelope, source defaulting to the request host
+func cloudEvent(options *Options, c echo.Context, id string, end time.Time, fields []zapcore.Field) []zapcore.Field {
+	if id == "" {
+		id = newUUID()
+	}
+
+	source := options.CloudEventsSource
+	if source == "" {
+		source = c.Request().Host
+	}
+
+	return []zapcore.Field{
+		zap.String("specversion", cloudEventsSpecVersion),
+		zap.String("type", CloudEventsType),
+		zap.String("source", source),
+		zap.String("id", id),
+		zap.String("time", end.UTC().Format(time.RFC3339Nano)),
+		zap.Object("data", fieldsObject(fields)),
+	}
+}
+
+// fieldsObject marshals fields as a zap object
+type fieldsObject []zapcore.Field
+
+func (f fieldsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range f {
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+// gcpHTTPRequest marshals a request as the HttpRequest object of Google Cloud Logging, sizes being
+// strings as int64 values are in its JSON mapping
+type gcpHTTPRequest struct {
+	c           echo.Context
+	remoteIP    string
+	target      string
+	status      int
+	latency     time.Duration
+	omitLatency bool
+}
+
+func (r gcpHTTPRequest) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	req := r.c.Request()
+
+	enc.AddString("requestMethod", req.Method)
+	enc.AddString("requestUrl", r.target)
+	if req.ContentLength > 0 {
+		enc.AddString("requestSize", strconv.FormatInt(req.ContentLength, 10))
+	}
+	enc.AddInt("status", r.status)
+	enc.AddString("responseSize", strconv.FormatInt(r.c.Response().Size, 10))
+	enc.AddString("userAgent", req.UserAgent())
+	enc.AddString("remoteIp", r.remoteIP)
+	if referer := req.Referer(); referer != "" {
+		enc.AddString("referer", referer)
+	}
+	if !r.omitLatency {
+		enc.AddString("latency", strconv.FormatFloat(r.latency.Seconds(), 'f', -1, 64)+"s")
+	}
+	enc.AddString("protocol", req.Proto)
+	return nil
+}
+
+// gcpSeverity returns the Google Cloud Logging severity of a zap level
+func gcpSeverity(level zapcore.Level) string {
+	switch level {
+	case zapcore.DebugLevel:
+		return "DEBUG"
+	case zapcore.InfoLevel:
+		return "INFO"
+	case zapcore.WarnLevel:
+		return "WARNING"
+	case zapcore.ErrorLevel:
+		return "ERROR"
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return "CRITICAL"
+	case zapcore.FatalLevel:
+		return "ALERT"
+	default:
+		return "DEFAULT"
+	}
+}
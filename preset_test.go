@@ -0,0 +1,231 @@
+package echozap
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerPresetECS(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users?page=2", nil)
+	req.Header.Set("User-Agent", "curl/7.64.1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusCreated, "created")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Preset: PresetECS,
+		Clock:  stepClock(time.Now(), 1500*time.Microsecond),
+	})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "192.0.2.1", logFields["client.ip"])
+	assert.Equal(t, int64(1500000), logFields["event.duration"])
+	assert.Equal(t, "example.com", logFields["url.domain"])
+	assert.Equal(t, "/users", logFields["url.path"])
+	assert.Equal(t, "/users?page=2", logFields["url.original"])
+	assert.Equal(t, http.MethodPost, logFields["http.request.method"])
+	assert.Equal(t, int64(http.StatusCreated), logFields["http.response.status_code"])
+	assert.Equal(t, int64(7), logFields["http.response.body.bytes"])
+	assert.Equal(t, "curl/7.64.1", logFields["user_agent.original"])
+
+	for _, key := range []string{"remote_ip", "latency", "host", "request", "status", "size", "user_agent"} {
+		assert.NotContains(t, logFields, key)
+	}
+}
+
+func TestZapLoggerPresetCloudEvents(t *testing.T) {
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Set(DefaultCustomFieldsKey, []zapcore.Field{zap.String("tenant", "acme")})
+		return c.String(http.StatusOK, "ok")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Preset: PresetCloudEvents,
+		Clock:  stepClock(start, 2*time.Millisecond),
+	})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "1.0", logFields["specversion"])
+	assert.Equal(t, CloudEventsType, logFields["type"])
+	assert.Equal(t, "example.com", logFields["source"])
+	assert.Equal(t, "abc", logFields["id"])
+	assert.Equal(t, "2019-11-21T10:00:00.002Z", logFields["time"])
+
+	data := logFields["data"].(map[string]interface{})
+	assert.Equal(t, "GET /users", data["request"])
+	assert.Equal(t, int64(http.StatusOK), data["status"])
+	assert.Equal(t, "2ms", data["latency"])
+	assert.Equal(t, "acme", data["tenant"])
+	assert.NotContains(t, logFields, "status")
+}
+
+func TestZapLoggerPresetCloudEventsGeneratesID(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), Preset: PresetCloudEvents, CloudEventsSource: "/api"})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "/api", logFields["source"])
+	assert.Len(t, logFields["id"], 36)
+}
+
+func TestZapLoggerOrderedFields(t *testing.T) {
+	expected := []string{"remote_ip", "latency", "host", "request", "status", "size", "user_agent"}
+
+	for i := 0; i < 3; i++ {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		}
+
+		var buf bytes.Buffer
+		logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zapcore.DebugLevel))
+
+		err := ZapLogger(&Options{Logger: logger, OrderedFields: true})(h)(c)
+
+		assert.Nil(t, err)
+
+		out := buf.String()
+		start := strings.Index(out, `"http":{`)
+		assert.True(t, start >= 0, out)
+
+		last := start
+		for _, key := range expected {
+			pos := strings.Index(out, `"`+key+`":`)
+			assert.True(t, pos > last, key)
+			last = pos
+		}
+	}
+}
+
+func TestZapLoggerFieldMapper(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("Referer", "https://example.com/")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/users/:id")
+
+	h := func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	var mapped error
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		FieldMapper: func(c echo.Context, start time.Time, err error) []zapcore.Field {
+			mapped = err
+			return []zapcore.Field{
+				zap.String("path", c.Path()),
+				zap.String("referer", c.Request().Referer()),
+				zap.Int("code", c.Response().Status),
+			}
+		},
+	})(h)(c)
+	assert.Nil(t, err)
+	assert.NotNil(t, mapped)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "/users/:id", logFields["path"])
+	assert.Equal(t, "https://example.com/", logFields["referer"])
+	assert.Equal(t, int64(http.StatusNotFound), logFields["code"])
+	assert.NotContains(t, logFields, "user_agent")
+	assert.NotContains(t, logFields, "host")
+}
+
+func TestZapLoggerPresetGCP(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users?page=2", strings.NewReader("{}"))
+	req.Header.Set("User-Agent", "curl/7.64.1")
+	req.Header.Set("Referer", "https://example.com/")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusServiceUnavailable, "unavailable")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Preset: PresetGCP,
+		Clock:  stepClock(time.Now(), 1500*time.Microsecond),
+	})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "ERROR", logFields["severity"])
+	assert.Equal(t, map[string]interface{}{
+		"requestMethod": http.MethodPost,
+		"requestUrl":    "/users?page=2",
+		"requestSize":   "2",
+		"status":        http.StatusServiceUnavailable,
+		"responseSize":  "11",
+		"userAgent":     "curl/7.64.1",
+		"remoteIp":      "192.0.2.1",
+		"referer":       "https://example.com/",
+		"latency":       "0.0015s",
+		"protocol":      "HTTP/1.1",
+	}, logFields["httpRequest"])
+
+	for _, key := range []string{"remote_ip", "latency", "host", "request", "status", "size", "user_agent"} {
+		assert.NotContains(t, logFields, key)
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	assert.Equal(t, "INFO", gcpSeverity(zapcore.InfoLevel))
+	assert.Equal(t, "WARNING", gcpSeverity(zapcore.WarnLevel))
+	assert.Equal(t, "CRITICAL", gcpSeverity(zapcore.PanicLevel))
+}
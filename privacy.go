@@ -0,0 +1,43 @@
+package echozap
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// clientIPHeaders are the request headers carrying client IPs, anonymized with Options.AnonymizeIP
+var clientIPHeaders = []string{echo.HeaderXForwardedFor, http.CanonicalHeaderKey(echo.HeaderXRealIP)}
+
+// remoteIP returns the client IP of the request, anonymized when Options.AnonymizeIP is set
+func remoteIP(options *Options, c echo.Context) string {
+	ip := c.RealIP()
+	if options.AnonymizeIP {
+		return anonymizeIP(ip)
+	}
+	return ip
+}
+
+// anonymizeIPHeader anonymizes the comma-separated IPs of a client IP header value
+func anonymizeIPHeader(value string) string {
+	ips := strings.Split(value, ",")
+	for i, ip := range ips {
+		ips[i] = anonymizeIP(strings.TrimSpace(ip))
+	}
+	return strings.Join(ips, ", ")
+}
+
+// anonymizeIP zeroes the last octet of an IPv4 address and the last 80 bits of an IPv6 one. Values that
+// aren't IP addresses are returned as is.
+func anonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
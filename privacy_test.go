@@ -0,0 +1,165 @@
+package echozap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.123":                          "192.0.2.0",
+		"2001:db8:85a3:8d3:1319:8a2e:370:7348": "2001:db8:85a3::",
+		"::ffff:192.0.2.123":                   "192.0.2.0",
+		"unknown":                              "unknown",
+		"":                                     "",
+	}
+
+	for ip, anonymized := range tests {
+		assert.Equal(t, anonymized, anonymizeIP(ip), ip)
+	}
+}
+
+func TestZapLoggerAnonymizeIP(t *testing.T) {
+	for preset, key := range map[Preset]string{PresetDefault: "remote_ip", PresetECS: "client.ip"} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/something", nil)
+		req.Header.Set(echo.HeaderXRealIP, "203.0.113.42")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), Preset: preset, AnonymizeIP: true})(h)(c)
+		assert.Nil(t, err)
+		assert.Equal(t, "203.0.113.0", logs.All()[0].ContextMap()[key])
+	}
+}
+
+func TestZapLoggerAnonymizeIPEverywhere(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something?debug=1", nil)
+	req.RemoteAddr = "198.51.100.7:5233"
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.42, 198.51.100.7")
+	req.Header.Set(echo.HeaderXRealIP, "203.0.113.42")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:          zap.New(obs),
+		AnonymizeIP:     true,
+		LogRemoteAddr:   true,
+		DebugQueryParam: "debug",
+	})(h)(c)
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "198.51.100.0", logFields["remote_addr"])
+	headers := logFields["request_headers"].(map[string]interface{})
+	assert.Equal(t, "203.0.113.0, 198.51.100.0", headers[echo.HeaderXForwardedFor])
+	assert.Equal(t, "203.0.113.0", headers["X-Real-Ip"])
+
+	obs, logs = observer.New(zap.DebugLevel)
+
+	err = ZapLogger(&Options{
+		Logger:            zap.New(obs),
+		AnonymizeIP:       true,
+		LogRequestHeaders: []string{echo.HeaderXForwardedFor},
+		Values:            &RequestLoggerValues{LogHeaders: []string{echo.HeaderXRealIP}},
+	})(h)(c)
+	assert.Nil(t, err)
+
+	logFields = logs.All()[0].ContextMap()
+	assert.Equal(t, map[string]interface{}{echo.HeaderXForwardedFor: "203.0.113.0, 198.51.100.0"}, logFields["request_headers"])
+	assert.Equal(t, map[string]interface{}{echo.HeaderXRealIP: []interface{}{"203.0.113.0"}}, logFields["headers"])
+}
+
+func TestZapLoggerFieldScrubber(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/reset?token=secret", nil)
+	req.Header.Set("User-Agent", "curl/7.64.1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		AddFields(c, zap.String("email", "bob@example.com"))
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		FieldScrubber: func(field zapcore.Field) zapcore.Field {
+			switch field.Key {
+			case "user_agent", "email":
+				return zap.String(field.Key, "[SCRUBBED]")
+			case "request":
+				return zap.String(field.Key, strings.SplitN(field.String, "?", 2)[0])
+			}
+			return field
+		},
+	})(h)(c)
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "[SCRUBBED]", logFields["user_agent"])
+	assert.Equal(t, "[SCRUBBED]", logFields["email"])
+	assert.Equal(t, "GET /reset", logFields["request"])
+	assert.Equal(t, int64(http.StatusOK), logFields["status"])
+
+	h = func(c echo.Context) error {
+		return errors.New("no account for bob@example.com")
+	}
+
+	obs, logs = observer.New(zap.DebugLevel)
+
+	err = ZapLogger(&Options{
+		Logger: zap.New(obs),
+		FieldScrubber: func(field zapcore.Field) zapcore.Field {
+			if field.Key == "error" {
+				return zap.String(field.Key, "[SCRUBBED]")
+			}
+			return field
+		},
+	})(h)(e.NewContext(httptest.NewRequest(http.MethodGet, "/reset", nil), httptest.NewRecorder()))
+	assert.Nil(t, err)
+	assert.Equal(t, "[SCRUBBED]", logs.All()[0].ContextMap()["error"])
+}
+
+func TestAnonymizeIPEventAndRecover(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	events := make(chan LogEvent, 2)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.NewNop(), AnonymizeIP: true, EventSink: events}))
+	e.Use(ZapRecover(&RecoverOptions{Logger: zap.New(obs), AnonymizeIP: true}))
+	e.GET("/", func(c echo.Context) error {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "203.0.113.0", (<-events).RemoteIP)
+	assert.Equal(t, "203.0.113.0", logs.All()[0].ContextMap()["remote_ip"])
+}
@@ -0,0 +1,42 @@
+package echozap
+
+import (
+	"io"
+	"time"
+)
+
+// bodyReader wraps a request body to observe how the handler consumes it. It doesn't buffer
+// beyond the optional capture.
+type bodyReader struct {
+	io.ReadCloser
+	// clock enables timing the reads when set
+	clock func() time.Time
+	// capture keeps a copy of the bytes read when set
+	capture *limitedBuffer
+
+	// elapsed is the total time spent in Read calls
+	elapsed time.Duration
+	// bytesRead is the number of bytes read by the handler
+	bytesRead int64
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	if r.clock == nil {
+		return r.read(p)
+	}
+
+	start := r.clock()
+	n, err := r.read(p)
+	r.elapsed += r.clock().Sub(start)
+	return n, err
+}
+
+// read reads from the body, capturing what was read
+func (r *bodyReader) read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.bytesRead += int64(n)
+	if r.capture != nil && n > 0 {
+		r.capture.Write(p[:n])
+	}
+	return n, err
+}
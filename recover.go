@@ -0,0 +1,97 @@
+package echozap
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultRecoverStackSize is the default size of the stack trace logged by ZapRecover.
+const DefaultRecoverStackSize = 4 << 10
+
+// RecoverOptions configures ZapRecover.
+type RecoverOptions struct {
+	// Logger is the zap logger panics are logged with
+	Logger *zap.Logger
+	// Preset selects the names and layout of the request fields, as for the access log
+	Preset Preset
+	// AnonymizeIP anonymizes the logged client IP, as Options.AnonymizeIP does for the access log
+	AnonymizeIP bool
+	// StackSize is the maximum size in bytes of the logged stack trace (default: DefaultRecoverStackSize)
+	StackSize int
+	// DisableStack disables capturing and logging the stack trace
+	DisableStack bool
+	// StackAll captures the stack traces of all goroutines instead of the panicking one
+	StackAll bool
+	// PanicInfoKey is the context key the recovered panic is stored under as a PanicInfo, for
+	// Options.PanicInfoKey of the access log. Empty disables it.
+	PanicInfoKey string
+	// Clock returns the current time, used to measure requests (default: time.Now)
+	Clock func() time.Time
+}
+
+// ZapRecover is a middleware recovering from panics in the handler chain. The panic is logged at error
+// level with the request fields of the access log, "panic_message" and "panic_stack", then passed to
+// the echo error handler as a 500 error. Register it after ZapLogger so the access log carries the
+// final status.
+func ZapRecover(options *RecoverOptions) echo.MiddlewareFunc {
+	if options.StackSize <= 0 {
+		options.StackSize = DefaultRecoverStackSize
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			start := options.Clock()
+
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+
+				info := PanicInfo{Value: value}
+				if !options.DisableStack {
+					stack := make([]byte, options.StackSize)
+					info.Stack = stack[:runtime.Stack(stack, options.StackAll)]
+				}
+				if options.PanicInfoKey != "" {
+					c.Set(options.PanicInfoKey, info)
+				}
+
+				panicErr, ok := value.(error)
+				if !ok {
+					panicErr = fmt.Errorf("%v", value)
+				}
+				c.Error(echo.NewHTTPError(http.StatusInternalServerError).SetInternal(panicErr))
+
+				fields := standardFields(nil, &Options{Preset: options.Preset, AnonymizeIP: options.AnonymizeIP}, c, c.Response().Status, options.Clock().Sub(start))
+				if id := requestID(c); id != "" {
+					fields = append(fields, zap.String("request_id", id))
+				}
+				fields = append(fields, recoverFields(info)...)
+
+				options.Logger.Error("Panic recovered", fields...)
+				err = nil
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// recoverFields returns the fields describing a recovered panic
+func recoverFields(info PanicInfo) []zapcore.Field {
+	fields := []zapcore.Field{zap.String("panic_message", panicMessage(info.Value))}
+	if len(info.Stack) > 0 {
+		fields = append(fields, zap.ByteString("panic_stack", info.Stack))
+	}
+	return fields
+}
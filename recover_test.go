@@ -0,0 +1,90 @@
+package echozap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapRecover(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(obs)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: logger, PanicInfoKey: "panic"}))
+	e.Use(ZapRecover(&RecoverOptions{Logger: logger, PanicInfoKey: "panic"}))
+	e.GET("/", func(c echo.Context) error {
+		panic("nil map")
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, 2, logs.Len())
+
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	assert.Equal(t, "Panic recovered", entry.Message)
+
+	logFields := entry.ContextMap()
+	assert.Equal(t, "nil map", logFields["panic_message"])
+	assert.Contains(t, logFields["panic_stack"], "goroutine")
+	assert.Equal(t, "abc", logFields["request_id"])
+	assert.Equal(t, "GET /", logFields["request"])
+	assert.Equal(t, int64(http.StatusInternalServerError), logFields["status"])
+
+	logFields = logs.All()[1].ContextMap()
+	assert.Equal(t, int64(http.StatusInternalServerError), logFields["status"])
+	assert.Equal(t, true, logFields["panic_recovered"])
+	assert.Equal(t, "nil map", logFields["panic_message"])
+}
+
+func TestZapRecoverStack(t *testing.T) {
+	h := func(c echo.Context) error {
+		panic(errors.New("boom"))
+	}
+
+	for _, options := range []*RecoverOptions{{DisableStack: true}, {StackSize: 16}} {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+		obs, logs := observer.New(zap.DebugLevel)
+		options.Logger = zap.New(obs)
+
+		err := ZapRecover(options)(h)(c)
+		assert.Nil(t, err)
+
+		logFields := logs.All()[0].ContextMap()
+		assert.Equal(t, "boom", logFields["panic_message"])
+		if options.DisableStack {
+			assert.NotContains(t, logFields, "panic_stack")
+		} else {
+			assert.Len(t, logFields["panic_stack"], 16)
+		}
+	}
+}
+
+func TestZapRecoverWithoutPanic(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapRecover(&RecoverOptions{Logger: zap.New(obs)})(h)(c)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, logs.Len())
+}
@@ -0,0 +1,48 @@
+package echozap
+
+import (
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ResolveByHost returns an Options.LoggerResolver selecting the logger of the request host, lower cased and
+// without its port. Requests to other hosts use the default logger.
+func ResolveByHost(loggers map[string]*zap.Logger) func(c echo.Context) *zap.Logger {
+	return func(c echo.Context) *zap.Logger {
+		host := c.Request().Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return loggers[strings.ToLower(host)]
+	}
+}
+
+// ResolveByHeader returns an Options.LoggerResolver selecting the logger of the value of a request
+// header, such as a tenant id set by the gateway. Other values use the default logger.
+func ResolveByHeader(name string, loggers map[string]*zap.Logger) func(c echo.Context) *zap.Logger {
+	return func(c echo.Context) *zap.Logger {
+		return loggers[c.Request().Header.Get(name)]
+	}
+}
+
+// ResolveByPathPrefix returns an Options.LoggerResolver selecting the logger of the longest prefix of the
+// request path. Other paths use the default logger.
+func ResolveByPathPrefix(loggers map[string]*zap.Logger) func(c echo.Context) *zap.Logger {
+	return func(c echo.Context) *zap.Logger {
+		path := c.Request().URL.Path
+
+		var (
+			logger  *zap.Logger
+			longest = -1
+		)
+		for prefix, l := range loggers {
+			if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+				logger, longest = l, len(prefix)
+			}
+		}
+		return logger
+	}
+}
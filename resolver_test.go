@@ -0,0 +1,56 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestResolvers(t *testing.T) {
+	acmeCore, acme := observer.New(zap.DebugLevel)
+	globexCore, globex := observer.New(zap.DebugLevel)
+	defaultCore, fallback := observer.New(zap.DebugLevel)
+
+	tests := []struct {
+		resolver func(c echo.Context) *zap.Logger
+		acme     func(req *http.Request)
+		globex   func(req *http.Request)
+	}{
+		{
+			resolver: ResolveByHost(map[string]*zap.Logger{"acme.example.com": zap.New(acmeCore), "globex.example.com": zap.New(globexCore)}),
+			acme:     func(req *http.Request) { req.Host = "ACME.example.com:8080" },
+			globex:   func(req *http.Request) { req.Host = "globex.example.com" },
+		},
+		{
+			resolver: ResolveByHeader("X-Tenant", map[string]*zap.Logger{"acme": zap.New(acmeCore), "globex": zap.New(globexCore)}),
+			acme:     func(req *http.Request) { req.Header.Set("X-Tenant", "acme") },
+			globex:   func(req *http.Request) { req.Header.Set("X-Tenant", "globex") },
+		},
+		{
+			resolver: ResolveByPathPrefix(map[string]*zap.Logger{"/tenants/": zap.New(acmeCore), "/tenants/globex/": zap.New(globexCore)}),
+			acme:     func(req *http.Request) { req.URL.Path = "/tenants/acme/users" },
+			globex:   func(req *http.Request) { req.URL.Path = "/tenants/globex/users" },
+		},
+	}
+
+	for i, test := range tests {
+		h := ZapLogger(&Options{Logger: zap.New(defaultCore), LoggerResolver: test.resolver})(func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		})
+
+		for _, setup := range []func(req *http.Request){test.acme, test.globex, func(*http.Request) {}} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			setup(req)
+			assert.Nil(t, h(echo.New().NewContext(req, httptest.NewRecorder())))
+		}
+
+		assert.Equal(t, i+1, acme.Len())
+		assert.Equal(t, i+1, globex.Len())
+		assert.Equal(t, i+1, fallback.Len())
+	}
+}
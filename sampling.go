@@ -0,0 +1,47 @@
+package echozap
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorSampleTick is the interval over which identical error entries are counted
+const errorSampleTick = time.Second
+
+// newErrorSampler wraps logger so that, each second, only the first entries with the same message are
+// logged and then every thereafter-th one. A thereafter below 1 drops every entry past the first ones.
+func newErrorSampler(logger *zap.Logger, first, thereafter int) *zap.Logger {
+	if thereafter < 1 {
+		thereafter = math.MaxInt32
+	}
+
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSampler(core, errorSampleTick, first, thereafter)
+	}))
+}
+
+// Sampler decides whether the entry of a request is kept. It is called concurrently.
+type Sampler func(c echo.Context) bool
+
+// NewRateSampler returns a Sampler keeping a fixed fraction of the requests, between 0 and 1. The
+// decision is deterministic: with a rate of 0.25, every fourth request is kept.
+func NewRateSampler(rate float64) Sampler {
+	var (
+		mu    sync.Mutex
+		count float64
+	)
+
+	return func(echo.Context) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		before := math.Floor(count * rate)
+		count++
+		return math.Floor(count*rate) > before
+	}
+}
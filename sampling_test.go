@@ -0,0 +1,170 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerErrorSampling(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), ErrorSampleFirst: 3, ErrorSampleThereafter: 5}))
+	e.GET("/down", func(c echo.Context) error {
+		return c.String(http.StatusServiceUnavailable, "")
+	})
+	e.GET("/up", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	for i := 0; i < 20; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/down", nil))
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/up", nil))
+	}
+
+	// 3 first entries, then the 8th, 13th and 18th
+	assert.Equal(t, 6, logs.FilterField(zap.Int("status", http.StatusServiceUnavailable)).Len())
+	assert.Equal(t, 20, logs.FilterField(zap.Int("status", http.StatusOK)).Len())
+
+	for _, entry := range logs.FilterField(zap.Int("status", http.StatusServiceUnavailable)).All() {
+		assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	}
+}
+
+func TestZapLoggerErrorSamplingScoped(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), InjectScopedLogger: true, ErrorSampleFirst: 3, ErrorSampleThereafter: 5}))
+	e.GET("/down", func(c echo.Context) error {
+		return c.String(http.StatusServiceUnavailable, "")
+	})
+
+	for i := 0; i < 20; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/down", nil))
+	}
+
+	assert.Equal(t, 6, logs.Len())
+	for _, entry := range logs.All() {
+		assert.Equal(t, "/down", entry.ContextMap()["route"])
+		assert.Equal(t, http.MethodGet, entry.ContextMap()["method"])
+	}
+}
+
+func TestNewRateSampler(t *testing.T) {
+	sample := NewRateSampler(0.25)
+
+	var decisions []bool
+	for i := 0; i < 8; i++ {
+		decisions = append(decisions, sample(nil))
+	}
+
+	assert.Equal(t, []bool{false, false, false, true, false, false, false, true}, decisions)
+
+	all, none := NewRateSampler(1), NewRateSampler(0)
+	for i := 0; i < 5; i++ {
+		assert.True(t, all(nil))
+		assert.False(t, none(nil))
+	}
+}
+
+func TestZapLoggerSampledField(t *testing.T) {
+	for _, logSampledOut := range []bool{true, false} {
+		obs, logs := observer.New(zap.DebugLevel)
+
+		e := echo.New()
+		e.Use(ZapLogger(&Options{Logger: zap.New(obs), Sampler: NewRateSampler(0.5), LogSampledOut: logSampledOut}))
+		e.GET("/", func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		})
+
+		for i := 0; i < 4; i++ {
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+
+		kept := logs.FilterField(zap.Bool("sampled", true)).All()
+		assert.Len(t, kept, 2)
+		for _, entry := range kept {
+			assert.Equal(t, zapcore.InfoLevel, entry.Level)
+		}
+
+		rejected := logs.FilterField(zap.Bool("sampled", false)).All()
+		if logSampledOut {
+			assert.Len(t, rejected, 2)
+			for _, entry := range rejected {
+				assert.Equal(t, zapcore.DebugLevel, entry.Level)
+			}
+		} else {
+			assert.Len(t, rejected, 0)
+		}
+	}
+}
+
+func TestZapLoggerLowPriorityPaths(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	healthy := true
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger:             zap.New(obs),
+		LowPriorityPaths:   []string{"/healthz"},
+		LowPrioritySampler: NewRateSampler(0.25),
+	}))
+	e.GET("/healthz", func(c echo.Context) error {
+		if !healthy {
+			return c.String(http.StatusServiceUnavailable, "")
+		}
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/users", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	for i := 0; i < 8; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Equal(t, 2, logs.Len())
+
+	healthy = false
+	for i := 0; i < 3; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Equal(t, 5, logs.Len())
+	assert.Equal(t, 3, logs.FilterMessage("Server: Service Unavailable").Len())
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, 6, logs.Len())
+}
+
+func TestZapLoggerSuccessSampleRate(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), SuccessSampleRate: 0.25}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return c.String(http.StatusInternalServerError, "")
+	})
+
+	for i := 0; i < 8; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, 2, logs.FilterField(zap.Bool("sampled", true)).Len())
+
+	for i := 0; i < 3; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	}
+	assert.Equal(t, 5, logs.Len())
+	assert.Equal(t, 3, logs.FilterMessage("Server: Internal Server Error").Len())
+}
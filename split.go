@@ -0,0 +1,34 @@
+package echozap
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSplitLogger returns a JSON logger sending info-and-below entries to out and warn-and-above entries
+// to errOut, e.g. stdout and stderr. Use it as Options.Logger to separate the streams.
+func NewSplitLogger(out, errOut zapcore.WriteSyncer) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+
+	low := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return level < zapcore.WarnLevel
+	})
+	high := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return level >= zapcore.WarnLevel
+	})
+
+	return zap.New(zapcore.NewTee(
+		zapcore.NewCore(encoder, out, low),
+		zapcore.NewCore(encoder.Clone(), errOut, high),
+	))
+}
+
+// NewTeeLogger returns a logger writing each entry both as JSON to jsonOut, e.g. a file, and in the
+// colored console format to consoleOut, e.g. stdout, each at its own minimum level. Use it as
+// Options.Logger while migrating between formats.
+func NewTeeLogger(jsonOut zapcore.WriteSyncer, jsonLevel zapcore.LevelEnabler, consoleOut zapcore.WriteSyncer, consoleLevel zapcore.LevelEnabler) *zap.Logger {
+	return zap.New(zapcore.NewTee(
+		zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), jsonOut, jsonLevel),
+		zapcore.NewCore(newDevEncoder(), consoleOut, consoleLevel),
+	))
+}
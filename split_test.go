@@ -0,0 +1,72 @@
+package echozap
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewSplitLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: NewSplitLogger(zapcore.AddSync(out), zapcore.AddSync(errOut))}))
+	e.GET("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return c.String(http.StatusNotFound, "")
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return c.String(http.StatusInternalServerError, "")
+	})
+
+	for _, path := range []string{"/ok", "/missing", "/broken"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+	assert.Contains(t, out.String(), `"level":"info"`)
+	assert.Contains(t, out.String(), "GET /ok")
+
+	assert.Equal(t, 2, strings.Count(errOut.String(), "\n"))
+	assert.Contains(t, errOut.String(), `"level":"warn"`)
+	assert.Contains(t, errOut.String(), `"level":"error"`)
+	assert.NotContains(t, errOut.String(), "GET /ok")
+}
+
+func TestNewTeeLogger(t *testing.T) {
+	jsonOut := &bytes.Buffer{}
+	consoleOut := &bytes.Buffer{}
+
+	logger := NewTeeLogger(zapcore.AddSync(jsonOut), zapcore.InfoLevel, zapcore.AddSync(consoleOut), zapcore.WarnLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: logger}))
+	e.GET("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return c.String(http.StatusNotFound, "")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.True(t, strings.HasPrefix(jsonOut.String(), "{"))
+	assert.Contains(t, jsonOut.String(), `"msg":"Client: Not Found"`)
+	assert.False(t, strings.HasPrefix(consoleOut.String(), "{"))
+	assert.Contains(t, consoleOut.String(), "\tClient: Not Found\t")
+
+	// below the console level
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	assert.Equal(t, 2, strings.Count(jsonOut.String(), "\n"))
+	assert.Equal(t, 1, strings.Count(consoleOut.String(), "\n"))
+}
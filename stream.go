@@ -0,0 +1,81 @@
+package echozap
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// connectionTypeSSE is the connection type of server-sent event streams
+const connectionTypeSSE = "sse"
+
+// upgradeType returns the lower-cased protocol a request asks to upgrade to, e.g. "websocket", or ""
+func upgradeType(req *http.Request) string {
+	upgrade := req.Header.Get("Upgrade")
+	if upgrade == "" || !hasToken(req.Header.Get("Connection"), "upgrade") {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(upgrade, ",", 2)[0]))
+}
+
+// hasToken reports whether the comma-separated header value contains token, ignoring case
+func hasToken(value, token string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEventStream reports whether a response content type is that of server-sent events
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// connectionOpenedFields returns the fields of the "Connection opened" entry
+func connectionOpenedFields(options *Options, c echo.Context, kind string) []zapcore.Field {
+	req := c.Request()
+	fields := []zapcore.Field{
+		zap.String("connection_type", kind),
+		zap.String("request", req.Method+" "+requestTarget(options, req)),
+		zap.String("remote_ip", remoteIP(options, c)),
+	}
+	if !options.InjectScopedLogger {
+		fields = append(fields, zap.String("request_id", requestID(c)))
+	}
+	return fields
+}
+
+// countingConn counts the bytes transferred on a hijacked connection. The counters are updated
+// atomically as connections are often read and written from different goroutines.
+type countingConn struct {
+	// read and written come first to be 64-bit aligned for the atomic operations
+	read    int64
+	written int64
+	net.Conn
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(&c.read, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(&c.written, int64(n))
+	return n, err
+}
+
+// bytes returns the numbers of bytes read and written so far
+func (c *countingConn) bytes() (int64, int64) {
+	return atomic.LoadInt64(&c.read), atomic.LoadInt64(&c.written)
+}
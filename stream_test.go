@@ -0,0 +1,166 @@
+package echozap
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestUpgradeType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	assert.Equal(t, "", upgradeType(req))
+
+	req.Header.Set("Upgrade", "WebSocket")
+	assert.Equal(t, "", upgradeType(req))
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	assert.Equal(t, "websocket", upgradeType(req))
+}
+
+func TestIsEventStream(t *testing.T) {
+	assert.True(t, isEventStream("text/event-stream"))
+	assert.True(t, isEventStream("text/event-stream; charset=utf-8"))
+	assert.False(t, isEventStream("text/plain"))
+	assert.False(t, isEventStream(""))
+}
+
+func TestZapLoggerEventStream(t *testing.T) {
+	start := time.Date(2019, 11, 21, 10, 0, 0, 0, time.UTC)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	h := func(c echo.Context) error {
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.WriteHeader(http.StatusOK)
+		assert.Equal(t, 1, logs.Len())
+
+		for i := 0; i < 2; i++ {
+			if _, err := io.WriteString(res, "data: tick\n\n"); err != nil {
+				return err
+			}
+			res.Flush()
+		}
+		return nil
+	}
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogConnections: true, Clock: stepClock(start, time.Minute)})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, logs.Len())
+
+	opened := logs.All()[0]
+	assert.Equal(t, "Connection opened", opened.Message)
+	assert.Equal(t, map[string]interface{}{
+		"connection_type": "sse",
+		"request":         "GET /events",
+		"remote_ip":       "192.0.2.1",
+		"request_id":      "req-1",
+	}, opened.ContextMap())
+
+	closed := logs.All()[1]
+	assert.Equal(t, "Connection closed", closed.Message)
+	fields := closed.ContextMap()
+	assert.Equal(t, "sse", fields["connection_type"])
+	assert.True(t, fields["duration"].(time.Duration) > 0)
+	assert.Equal(t, int64(0), fields["bytes_in"])
+	assert.Equal(t, int64(24), fields["bytes_out"])
+}
+
+func TestZapLoggerWebSocket(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), LogConnections: true}))
+	e.GET("/ws", func(c echo.Context) error {
+		conn, _, err := c.Response().Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if _, err := io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"); err != nil {
+			return err
+		}
+		ping := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ping); err != nil {
+			return err
+		}
+		_, err = conn.Write([]byte("pong"))
+		return err
+	})
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	assert.Nil(t, err)
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+	_, err = conn.Write([]byte("ping"))
+	assert.Nil(t, err)
+	pong := make([]byte, 4)
+	_, err = io.ReadFull(reader, pong)
+	assert.Nil(t, err)
+	assert.Equal(t, "pong", string(pong))
+
+	// the entry is written once the handler returned, after its last write
+	for i := 0; i < 100 && logs.Len() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "Connection opened", logs.All()[0].Message)
+	assert.Equal(t, "websocket", logs.All()[0].ContextMap()["connection_type"])
+
+	closed := logs.All()[1]
+	assert.Equal(t, "Connection closed", closed.Message)
+	fields := closed.ContextMap()
+	assert.Equal(t, "websocket", fields["connection_type"])
+	assert.Equal(t, int64(http.StatusSwitchingProtocols), fields["status"])
+	assert.Equal(t, int64(4), fields["bytes_in"])
+	assert.Equal(t, int64(len("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")+4), fields["bytes_out"])
+}
+
+func TestZapLoggerWithoutConnections(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		return c.String(http.StatusOK, "data: tick\n\n")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs)})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, logs.Len())
+	assert.NotContains(t, logs.All()[0].ContextMap(), "connection_type")
+}
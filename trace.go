@@ -0,0 +1,71 @@
+package echozap
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// headerTraceParent is the W3C trace context header
+const headerTraceParent = "Traceparent"
+
+// TraceContext identifies the span a request belongs to, as hex strings. Empty IDs aren't logged.
+type TraceContext struct {
+	TraceID string
+	// SpanID is the span of the request itself, as started by a tracing middleware
+	SpanID string
+	// ParentSpanID is the span of the caller, as propagated in the request headers
+	ParentSpanID string
+	Flags        string
+}
+
+// TraceExtractor returns the trace context of a request, false when it has none. Tracing libraries
+// keeping the active span on the request context, such as OpenTelemetry, plug in through it: echozap
+// can't depend on them itself, as they require more recent versions of Go and zap.
+type TraceExtractor func(c echo.Context) (TraceContext, bool)
+
+// TraceFromTraceParent extracts the trace context of the W3C traceparent request header. The span it
+// carries is the caller's, so it is returned as the ParentSpanID; requests without the header, such as
+// those starting a trace, have no trace context.
+func TraceFromTraceParent(c echo.Context) (TraceContext, bool) {
+	tp, ok := parseTraceParent(c.Request().Header.Get(headerTraceParent))
+	if !ok {
+		return TraceContext{}, false
+	}
+	return TraceContext{TraceID: tp.traceID, ParentSpanID: tp.spanID, Flags: tp.flags}, true
+}
+
+// traceParent is a parsed W3C traceparent header
+type traceParent struct {
+	traceID string
+	spanID  string
+	flags   string
+}
+
+// parseTraceParent parses a W3C traceparent header ("00-<trace id>-<span id>-<flags>")
+func parseTraceParent(value string) (traceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return traceParent{}, false
+	}
+
+	tp := traceParent{traceID: parts[1], spanID: parts[2], flags: parts[3]}
+	if !isHexID(tp.traceID, 32) || !isHexID(tp.spanID, 16) || len(tp.flags) != 2 {
+		return traceParent{}, false
+	}
+	if _, err := hex.DecodeString(tp.flags); err != nil {
+		return traceParent{}, false
+	}
+
+	return tp, true
+}
+
+// isHexID reports whether id is a lower case hex string of the given length that isn't all zeros
+func isHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" || strings.ToLower(id) != id {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,81 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tp, ok := parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, traceParent{traceID: "4bf92f3577b34da6a3ce929d0e0e4736", spanID: "00f067aa0ba902b7", flags: "01"}, tp)
+
+	for _, value := range []string{
+		"",
+		"garbage",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, ok := parseTraceParent(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestZapLoggerIncludeTraceContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(headerTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), IncludeTraceContext: true})(h)(c)
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logFields["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", logFields["parent_span_id"])
+	assert.NotContains(t, logFields, "span_id")
+	assert.Equal(t, "01", logFields["trace_flags"])
+}
+
+func TestZapLoggerTraceExtractor(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger:              zap.New(obs),
+		IncludeTraceContext: true,
+		TraceExtractor: func(echo.Context) (TraceContext, bool) {
+			return TraceContext{TraceID: "trace", SpanID: "span", Flags: "00"}, true
+		},
+	})(h)(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "span", logs.All()[0].ContextMap()["span_id"])
+	assert.NotContains(t, logs.All()[0].ContextMap(), "parent_span_id")
+
+	obs, logs = observer.New(zap.DebugLevel)
+
+	err = ZapLogger(&Options{Logger: zap.New(obs), IncludeTraceContext: true})(h)(c)
+	assert.Nil(t, err)
+	assert.NotContains(t, logs.All()[0].ContextMap(), "trace_id")
+}
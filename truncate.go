@@ -0,0 +1,104 @@
+package echozap
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// fieldOverhead is the estimated encoded size of a field besides its key and string value
+	fieldOverhead = 8
+	// truncateFloor is the size strings are never truncated below
+	truncateFloor = 32
+)
+
+// identifyingFields returns the keys of the fields identifying a request in the layout selected by
+// options, never truncated. The fields returned by a FieldMapper aren't known and may be truncated.
+func identifyingFields(options *Options) []string {
+	switch {
+	case options.FieldMapper != nil:
+		return []string{"request_id"}
+	case options.Values != nil:
+		return []string{"uri", "uri_path", "route_path", "method", "request_id", "remote_ip", "host", "latency"}
+	case options.Preset == PresetECS:
+		return []string{"url.original", "url.path", "url.domain", "http.request.method", "request_id", "client.ip", "event.duration"}
+	}
+	return []string{"request", "request_id", "remote_ip", "host", "latency"}
+}
+
+// fieldSize returns the size of the string value of a string or byte string field, or -1
+func fieldSize(f zapcore.Field) int {
+	switch f.Type {
+	case zapcore.StringType:
+		return len(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return len(b)
+		}
+	}
+	return -1
+}
+
+// estimateSize estimates the encoded size of an entry. Non string values count as fieldOverhead.
+func estimateSize(msg string, fields []zapcore.Field) int {
+	size := len(msg)
+	for _, f := range fields {
+		size += len(f.Key) + fieldOverhead
+		if n := fieldSize(f); n > 0 {
+			size += n
+		}
+	}
+	return size
+}
+
+// truncateFields shortens the largest string fields until the estimated size of the entry fits in
+// limit, adding a "truncated" field, keyed with prefix like the others, when anything was cut. The
+// fields keyed by identifying are kept and the others aren't cut below truncateFloor, so the limit may
+// not be met. fields is modified in place.
+func truncateFields(msg string, fields []zapcore.Field, limit int, prefix string, identifying []string) []zapcore.Field {
+	excess := estimateSize(msg, fields) - limit
+	if excess <= 0 {
+		return fields
+	}
+
+	truncated := false
+	for excess > 0 {
+		largest, size := -1, truncateFloor
+		for i, f := range fields {
+			if n := fieldSize(f); n > size && !containsString(identifying, strings.TrimPrefix(f.Key, prefix)) {
+				largest, size = i, n
+			}
+		}
+		if largest < 0 {
+			break
+		}
+
+		keep := size - excess
+		if keep < truncateFloor {
+			keep = truncateFloor
+		}
+		fields[largest] = truncateField(fields[largest], keep)
+		excess -= size - fieldSize(fields[largest])
+		truncated = true
+	}
+
+	if truncated {
+		fields = appendTopLevel(fields, zap.Bool(prefix+"truncated", true))
+	}
+	return fields
+}
+
+// truncateField keeps the first n bytes of a string or byte string field, not splitting UTF-8 characters
+func truncateField(f zapcore.Field, n int) zapcore.Field {
+	if f.Type == zapcore.ByteStringType {
+		return zap.ByteString(f.Key, f.Interface.([]byte)[:n])
+	}
+
+	for n > 0 && n < len(f.String) && !utf8.RuneStart(f.String[n]) {
+		n--
+	}
+	return zap.String(f.Key, f.String[:n])
+}
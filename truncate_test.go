@@ -0,0 +1,115 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTruncateFields(t *testing.T) {
+	fields := []zapcore.Field{
+		zap.String("small", "abc"),
+		zap.String("large", strings.Repeat("é", 100)),
+		zap.ByteString("body", []byte(strings.Repeat("x", 50))),
+		zap.Int("status", 200),
+	}
+
+	fields = truncateFields("msg", fields, 150, "", identifyingFields(&Options{}))
+
+	assert.True(t, estimateSize("msg", fields[:4]) <= 150)
+	assert.Equal(t, "abc", fields[0].String)
+	assert.True(t, len(fields[1].String) < 200)
+	assert.True(t, strings.HasPrefix(strings.Repeat("é", 100), fields[1].String))
+	assert.Equal(t, zap.Bool("truncated", true), fields[4])
+}
+
+func TestTruncateFieldsUnderLimit(t *testing.T) {
+	fields := []zapcore.Field{zap.String("small", "abc")}
+
+	assert.Equal(t, fields, truncateFields("msg", fields, 1024, "", identifyingFields(&Options{})))
+}
+
+func TestZapLoggerMaxEntryBytes(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.Header.Set("User-Agent", strings.Repeat("a", 4096))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), MaxEntryBytes: 512})(h)(c)
+
+	assert.Nil(t, err)
+
+	entry := logs.All()[0]
+	assert.Equal(t, true, entry.ContextMap()["truncated"])
+	assert.True(t, len(entry.ContextMap()["user_agent"].(string)) < 512)
+	assert.Equal(t, "GET /something", entry.ContextMap()["request"])
+}
+
+func TestZapLoggerMaxEntryBytesUnreachable(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something/with/a/rather/long/path", nil)
+	req.Header.Set(echo.HeaderXRequestID, "0b42b9a5-2b9a-4f39-8a8e-4bbf4c0a1d6f")
+	req.Header.Set("User-Agent", strings.Repeat("a", 4096))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		AddFields(c, zap.String("k", "v"))
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), MaxEntryBytes: 150, FieldPrefix: "http.", CustomFieldsNamespace: "app"})(h)(c)
+
+	assert.Nil(t, err)
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, true, fields["http.truncated"])
+	assert.Equal(t, "GET /something/with/a/rather/long/path", fields["http.request"])
+	assert.Equal(t, "0b42b9a5-2b9a-4f39-8a8e-4bbf4c0a1d6f", fields["http.request_id"])
+	assert.Equal(t, "example.com", fields["http.host"])
+	assert.Equal(t, "192.0.2.1", fields["http.remote_ip"])
+	assert.NotEmpty(t, fields["http.latency"])
+	assert.Equal(t, strings.Repeat("a", truncateFloor), fields["http.user_agent"])
+	assert.Equal(t, map[string]interface{}{"k": "v"}, fields["app"])
+}
+
+func TestZapLoggerMaxEntryBytesECS(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something/with/a/rather/long/path?and=a&rather=long&query=string", nil)
+	req.Header.Set("User-Agent", strings.Repeat("a", 4096))
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), MaxEntryBytes: 150, Preset: PresetECS})(h)(c)
+
+	assert.Nil(t, err)
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, true, fields["truncated"])
+	assert.Equal(t, "/something/with/a/rather/long/path?and=a&rather=long&query=string", fields["url.original"])
+	assert.Equal(t, "/something/with/a/rather/long/path", fields["url.path"])
+	assert.Equal(t, "192.0.2.1", fields["client.ip"])
+	assert.Contains(t, fields, "event.duration")
+	assert.Equal(t, strings.Repeat("a", truncateFloor), fields["user_agent.original"])
+}
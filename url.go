@@ -0,0 +1,112 @@
+package echozap
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue replaces the values of sensitive query parameters
+const redactedValue = "[REDACTED]"
+
+// urlObject marshals a request URL as a zap object
+type urlObject struct {
+	scheme string
+	host   string
+	path   string
+	query  string
+}
+
+func (u urlObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("scheme", u.scheme)
+	enc.AddString("host", u.host)
+	enc.AddString("path", u.path)
+	if u.query != "" {
+		enc.AddString("query", u.query)
+	}
+	return nil
+}
+
+// redactQuery returns the query with the values of the sensitive parameters replaced. It is returned
+// untouched when nothing needs redacting.
+func redactQuery(query url.Values, rawQuery string, sensitive []string) string {
+	redacted := false
+	for _, name := range sensitive {
+		if values, ok := query[name]; ok {
+			for i := range values {
+				values[i] = redactedValue
+			}
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return rawQuery
+	}
+	return query.Encode()
+}
+
+// loggedPath returns the URL path to log, replaced by its hash with Options.LogPathHash
+func loggedPath(options *Options, req *http.Request) string {
+	if options.LogPathHash {
+		return hashString(options.PathHashAlgorithm, req.URL.Path)
+	}
+	return req.URL.Path
+}
+
+// requestTarget returns the request URI to log: without its query with Options.StripQuery, or else
+// with the values of Options.SensitiveParams redacted. With Options.LogPathHash, the path is replaced
+// by its hash.
+func requestTarget(options *Options, req *http.Request) string {
+	if options.LogPathHash {
+		path := loggedPath(options, req)
+		if options.StripQuery || req.URL.RawQuery == "" {
+			return path
+		}
+		return path + "?" + redactQuery(req.URL.Query(), req.URL.RawQuery, options.SensitiveParams)
+	}
+	if options.StripQuery {
+		return strings.SplitN(req.RequestURI, "?", 2)[0]
+	}
+	if len(options.SensitiveParams) == 0 || req.URL.RawQuery == "" {
+		return req.RequestURI
+	}
+
+	query := redactQuery(req.URL.Query(), req.URL.RawQuery, options.SensitiveParams)
+	if query == req.URL.RawQuery {
+		return req.RequestURI
+	}
+	return strings.SplitN(req.RequestURI, "?", 2)[0] + "?" + query
+}
+
+// queryObject marshals query parameters as a zap object of string arrays, sorted by name, the values of
+// the sensitive parameters being redacted
+type queryObject struct {
+	query     url.Values
+	sensitive []string
+}
+
+func (q queryObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	names := make([]string, 0, len(q.query))
+	for name := range q.query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := q.query[name]
+		if containsString(q.sensitive, name) {
+			values = make([]string, len(values))
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+		if err := enc.AddArray(name, stringArray(values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
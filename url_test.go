@@ -0,0 +1,89 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerURLObject(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/search?q=shoes&api_key=secret&page=2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), URLObject: true, SensitiveParams: []string{"api_key"}})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"scheme": "https",
+		"host":   "example.com",
+		"path":   "/search",
+		"query":  "api_key=%5BREDACTED%5D&page=2&q=shoes",
+	}, logs.All()[0].ContextMap()["url"])
+}
+
+func TestRedactQuery(t *testing.T) {
+	raw := "b=2&a=1"
+	assert.Equal(t, raw, redactQuery(map[string][]string{"b": {"2"}, "a": {"1"}}, raw, []string{"token"}))
+	assert.Equal(t, "a=1&token=%5BREDACTED%5D&token=%5BREDACTED%5D",
+		redactQuery(map[string][]string{"token": {"x", "y"}, "a": {"1"}}, "token=x&a=1&token=y", []string{"token"}))
+}
+
+func TestZapLoggerQuery(t *testing.T) {
+	tests := []struct {
+		options Options
+		request string
+	}{
+		{options: Options{LogQuery: true, SensitiveParams: []string{"api_key"}}, request: "GET /search?api_key=%5BREDACTED%5D&page=2&q=shoes&q=boots"},
+		{options: Options{LogQuery: true, StripQuery: true}, request: "GET /search"},
+		{options: Options{}, request: "GET /search?q=shoes&api_key=secret&page=2&q=boots"},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/search?q=shoes&api_key=secret&page=2&q=boots", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		options := test.options
+		options.Logger = zap.New(obs)
+		err := ZapLogger(&options)(h)(c)
+
+		assert.Nil(t, err)
+		logFields := logs.All()[0].ContextMap()
+		assert.Equal(t, test.request, logFields["request"])
+
+		if !options.LogQuery {
+			assert.NotContains(t, logFields, "query")
+			continue
+		}
+
+		apiKey := "secret"
+		if len(options.SensitiveParams) > 0 {
+			apiKey = redactedValue
+		}
+		assert.Equal(t, map[string]interface{}{
+			"api_key": []interface{}{apiKey},
+			"page":    []interface{}{"2"},
+			"q":       []interface{}{"shoes", "boots"},
+		}, logFields["query"])
+	}
+}
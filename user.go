@@ -0,0 +1,109 @@
+package echozap
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultJWTContextKey is the context key echo's JWT middleware stores the token under.
+const DefaultJWTContextKey = "user"
+
+// JWTSubject returns an Options.UserResolver logging the subject ("sub" claim) of the token stored under
+// key by echo's JWT middleware as "user_id" (default key: echozap.DefaultJWTContextKey). Map claims,
+// claims with a GetSubject method and claims structs with a Subject field are supported, so no JWT
+// library is imported. Requests without a subject are not affected.
+func JWTSubject(key string) func(c echo.Context) []zapcore.Field {
+	if key == "" {
+		key = DefaultJWTContextKey
+	}
+
+	return func(c echo.Context) []zapcore.Field {
+		if subject := tokenSubject(c.Get(key)); subject != "" {
+			return []zapcore.Field{zap.String("user_id", subject)}
+		}
+		return nil
+	}
+}
+
+// ContextUser returns an Options.UserResolver logging the user id stored under key by an authentication
+// middleware, a string or a fmt.Stringer, as "user_id".
+func ContextUser(key string) func(c echo.Context) []zapcore.Field {
+	return func(c echo.Context) []zapcore.Field {
+		var id string
+		switch user := c.Get(key).(type) {
+		case string:
+			id = user
+		case fmt.Stringer:
+			id = user.String()
+		}
+
+		if id != "" {
+			return []zapcore.Field{zap.String("user_id", id)}
+		}
+		return nil
+	}
+}
+
+// subjectGetter is implemented by the claims of github.com/golang-jwt/jwt/v5
+type subjectGetter interface {
+	GetSubject() (string, error)
+}
+
+// tokenSubject returns the subject of a JWT token, read from the Claims field of the token
+func tokenSubject(token interface{}) string {
+	value := reflect.ValueOf(token)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+
+	claims := value.FieldByName("Claims")
+	if !claims.IsValid() || !claims.CanInterface() {
+		return ""
+	}
+	return claimsSubject(claims.Interface())
+}
+
+// claimsSubject returns the subject of JWT claims
+func claimsSubject(claims interface{}) string {
+	if getter, ok := claims.(subjectGetter); ok {
+		subject, _ := getter.GetSubject()
+		return subject
+	}
+
+	value := reflect.ValueOf(claims)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return ""
+		}
+		sub := value.MapIndex(reflect.ValueOf("sub").Convert(value.Type().Key()))
+		if !sub.IsValid() {
+			return ""
+		}
+		subject, _ := sub.Interface().(string)
+		return subject
+	case reflect.Struct:
+		sub := value.FieldByName("Subject")
+		if sub.IsValid() && sub.Kind() == reflect.String {
+			return sub.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package echozap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// token stands for the *jwt.Token stored by echo's JWT middleware
+type token struct {
+	Raw    string
+	Claims interface{}
+}
+
+// mapClaims stands for jwt.MapClaims
+type mapClaims map[string]interface{}
+
+// registeredClaims stands for jwt.RegisteredClaims
+type registeredClaims struct {
+	Issuer  string
+	Subject string
+}
+
+// customClaims stands for claims embedding jwt.RegisteredClaims
+type customClaims struct {
+	registeredClaims
+	Admin bool
+}
+
+// getterClaims stands for claims implementing the jwt/v5 Claims interface
+type getterClaims struct{}
+
+func (getterClaims) GetSubject() (string, error) { return "frank", nil }
+
+// failingClaims returns an error getting the subject
+type failingClaims struct{}
+
+func (failingClaims) GetSubject() (string, error) { return "", errors.New("no subject") }
+
+func TestTokenSubject(t *testing.T) {
+	tests := map[string]interface{}{
+		"alice": &token{Claims: mapClaims{"sub": "alice"}},
+		"bob":   &token{Claims: &registeredClaims{Subject: "bob"}},
+		"carol": token{Claims: customClaims{registeredClaims: registeredClaims{Subject: "carol"}}},
+		"frank": &token{Claims: getterClaims{}},
+		"":      &token{Claims: mapClaims{"sub": 42}},
+	}
+
+	for subject, value := range tests {
+		assert.Equal(t, subject, tokenSubject(value))
+	}
+
+	for _, value := range []interface{}{nil, (*token)(nil), "alice", &token{}, &token{Claims: failingClaims{}}} {
+		assert.Equal(t, "", tokenSubject(value))
+	}
+}
+
+func TestZapLoggerUserResolver(t *testing.T) {
+	resolvers := map[string]func(c echo.Context) []zapcore.Field{
+		"jwt":     JWTSubject(""),
+		"context": ContextUser("user_id"),
+	}
+
+	for name, resolver := range resolvers {
+		obs, logs := observer.New(zap.DebugLevel)
+
+		e := echo.New()
+		e.Use(ZapLogger(&Options{Logger: zap.New(obs), UserResolver: resolver}))
+		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+					c.Set(DefaultJWTContextKey, &token{Claims: mapClaims{"sub": "alice"}})
+					c.Set("user_id", "alice")
+				}
+				return next(c)
+			}
+		})
+		e.GET("/", func(c echo.Context) error {
+			return c.String(http.StatusOK, "")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "alice", logs.All()[0].ContextMap()["user_id"], name)
+		assert.NotContains(t, logs.All()[1].ContextMap(), "user_id", name)
+	}
+}
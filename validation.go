@@ -0,0 +1,45 @@
+package echozap
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// ValidationError describes a request field that failed validation
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (v ValidationError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("field", v.Field)
+	enc.AddString("message", v.Message)
+	return nil
+}
+
+// ValidationErrors is the list of field errors a validation middleware stores under
+// Options.ValidationErrorsKey
+type ValidationErrors []ValidationError
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (v ValidationErrors) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, err := range v {
+		if err := enc.AppendObject(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validationErrors returns the field errors stored in value, accepting both ValidationErrors and
+// []ValidationError
+func validationErrors(value interface{}) ValidationErrors {
+	switch errs := value.(type) {
+	case ValidationErrors:
+		return errs
+	case []ValidationError:
+		return errs
+	default:
+		return nil
+	}
+}
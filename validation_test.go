@@ -0,0 +1,60 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerValidationErrors(t *testing.T) {
+	values := []interface{}{
+		ValidationErrors{{Field: "email", Message: "invalid format"}, {Field: "age", Message: "must be positive"}},
+		[]ValidationError{{Field: "email", Message: "invalid format"}, {Field: "age", Message: "must be positive"}},
+	}
+
+	for _, value := range values {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := func(c echo.Context) error {
+			c.Set("validation", value)
+			return c.String(http.StatusBadRequest, "")
+		}
+
+		obs, logs := observer.New(zap.DebugLevel)
+
+		err := ZapLogger(&Options{Logger: zap.New(obs), ValidationErrorsKey: "validation"})(h)(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"field": "email", "message": "invalid format"},
+			map[string]interface{}{"field": "age", "message": "must be positive"},
+		}, logs.All()[0].ContextMap()["validation_errors"])
+	}
+}
+
+func TestZapLoggerValidationErrorsAbsent(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		c.Set("validation", "not a list")
+		return c.String(http.StatusBadRequest, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), ValidationErrorsKey: "validation"})(h)(c)
+
+	assert.Nil(t, err)
+	assert.NotContains(t, logs.All()[0].ContextMap(), "validation_errors")
+}
@@ -0,0 +1,164 @@
+package echozap
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestLoggerValues selects the standard fields to log, mirroring the value flags of echo's
+// middleware.RequestLoggerConfig to ease migrating from it. Set as Options.Values, it replaces the
+// standard fields of the Preset. Errors keep being logged with the entry of 4xx and 5xx responses.
+type RequestLoggerValues struct {
+	LogLatency       bool
+	LogProtocol      bool
+	LogRemoteIP      bool
+	LogHost          bool
+	LogMethod        bool
+	LogURI           bool
+	LogURIPath       bool
+	LogRoutePath     bool
+	LogRequestID     bool
+	LogReferer       bool
+	LogUserAgent     bool
+	LogStatus        bool
+	LogContentLength bool
+	LogResponseSize  bool
+	// LogHeaders are the request headers to log under "headers". Sensitive headers are masked and the
+	// others passed through Options.HeaderRedactor, as for Options.LogRequestHeaders.
+	LogHeaders []string
+	// LogQueryParams are the query parameters to log under "query_params"
+	LogQueryParams []string
+	// LogFormValues are the form values to log under "form_values"
+	LogFormValues []string
+}
+
+// fields returns the selected fields of the request, ip being its client IP, its URI and headers being
+// redacted as options tell
+func (v *RequestLoggerValues) fields(options *Options, c echo.Context, ip string, status int, latency time.Duration) []zapcore.Field {
+	req := c.Request()
+	res := c.Response()
+
+	var fields []zapcore.Field
+	if v.LogLatency && !latencyOmitted(options, latency) {
+		fields = append(fields, zap.Duration("latency", latency))
+	}
+	if v.LogProtocol {
+		fields = append(fields, zap.String("protocol", req.Proto))
+	}
+	if v.LogRemoteIP {
+		fields = append(fields, zap.String("remote_ip", ip))
+	}
+	if v.LogHost {
+		fields = append(fields, zap.String("host", req.Host))
+	}
+	if v.LogMethod {
+		fields = append(fields, zap.String("method", req.Method))
+	}
+	if v.LogURI {
+		fields = append(fields, zap.String("uri", requestTarget(options, req)))
+	}
+	if v.LogURIPath {
+		fields = append(fields, zap.String("uri_path", loggedPath(options, req)))
+	}
+	if v.LogRoutePath {
+		fields = append(fields, zap.String("route_path", matchedRoute(c)))
+	}
+	if v.LogRequestID {
+		fields = append(fields, zap.String("request_id", requestID(c)))
+	}
+	if v.LogReferer {
+		fields = append(fields, zap.String("referer", req.Referer()))
+	}
+	if v.LogUserAgent {
+		fields = append(fields, zap.String("user_agent", req.UserAgent()))
+	}
+	if v.LogStatus {
+		fields = append(fields, zap.Int("status", status))
+	}
+	if v.LogContentLength {
+		fields = append(fields, zap.String("content_length", req.Header.Get(echo.HeaderContentLength)))
+	}
+	if v.LogResponseSize {
+		fields = append(fields, zap.Int64("response_size", res.Size))
+	}
+	if len(v.LogHeaders) > 0 {
+		fields = append(fields, zap.Object("headers", valuesObject{values: url.Values(req.Header), names: v.LogHeaders, headers: true, redactor: options.HeaderRedactor, anonymize: options.AnonymizeIP}))
+	}
+	if len(v.LogQueryParams) > 0 {
+		fields = append(fields, zap.Object("query_params", valuesObject{values: req.URL.Query(), names: v.LogQueryParams}))
+	}
+	if len(v.LogFormValues) > 0 {
+		// ParseForm errors leave the form empty, the handler reports them
+		req.ParseForm()
+		fields = append(fields, zap.Object("form_values", valuesObject{values: req.Form, names: v.LogFormValues}))
+	}
+	return fields
+}
+
+// valuesObject marshals the wanted names of multi-valued request data as a zap object of string arrays
+type valuesObject struct {
+	values url.Values
+	names  []string
+	// headers looks names up in their canonical header form and masks the sensitive headers
+	headers bool
+	// redactor rewrites the values of the other headers when set
+	redactor func(name, value string) string
+	// anonymize anonymizes the client IP headers
+	anonymize bool
+}
+
+func (v valuesObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, name := range v.names {
+		key := name
+		if v.headers {
+			key = http.CanonicalHeaderKey(name)
+		}
+		if values, ok := v.values[key]; ok {
+			if v.headers {
+				values = redactHeaderValues(key, values, v.redactor, v.anonymize)
+			}
+			if err := enc.AddArray(name, stringArray(values)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// redactHeaderValues returns the values of the header name, masked when it is sensitive, anonymized when
+// it carries client IPs and anonymize is set, or else passed through redactor
+func redactHeaderValues(name string, values []string, redactor func(name, value string) string, anonymize bool) []string {
+	masked := containsString(sensitiveHeaders, name)
+	anonymized := anonymize && containsString(clientIPHeaders, name)
+	if !masked && !anonymized && redactor == nil {
+		return values
+	}
+
+	redacted := make([]string, len(values))
+	for i, value := range values {
+		switch {
+		case masked:
+			redacted[i] = redactedValue
+		case anonymized:
+			redacted[i] = anonymizeIPHeader(value)
+		default:
+			redacted[i] = redactor(name, value)
+		}
+	}
+	return redacted
+}
+
+// stringArray marshals strings as a zap array
+type stringArray []string
+
+func (a stringArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, s := range a {
+		enc.AppendString(s)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerValues(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Clock:  stepClock(time.Now(), 3*time.Millisecond),
+		Values: &RequestLoggerValues{
+			LogLatency:      true,
+			LogMethod:       true,
+			LogURIPath:      true,
+			LogRoutePath:    true,
+			LogStatus:       true,
+			LogResponseSize: true,
+			LogHeaders:      []string{"x-tenant"},
+			LogQueryParams:  []string{"page", "missing"},
+			LogFormValues:   []string{"name"},
+		},
+	}))
+	e.POST("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusCreated, "created")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/42?page=2&page=3", strings.NewReader("name=gopher"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set("X-Tenant", "acme")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, map[string]interface{}{
+		"latency":       3 * time.Millisecond,
+		"method":        http.MethodPost,
+		"uri_path":      "/users/42",
+		"route_path":    "/users/:id",
+		"status":        int64(http.StatusCreated),
+		"response_size": int64(7),
+		"headers":       map[string]interface{}{"x-tenant": []interface{}{"acme"}},
+		"query_params":  map[string]interface{}{"page": []interface{}{"2", "3"}},
+		"form_values":   map[string]interface{}{"name": []interface{}{"gopher"}},
+	}, logs.All()[0].ContextMap())
+}
+
+func TestZapLoggerValuesHeadersRedacted(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("X-Api-Key", "key")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{
+		Logger: zap.New(obs),
+		Values: &RequestLoggerValues{LogHeaders: []string{"authorization", "cookie", "x-api-key"}},
+		HeaderRedactor: func(name, value string) string {
+			return "<" + name + ">"
+		},
+	})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"authorization": []interface{}{redactedValue},
+		"cookie":        []interface{}{redactedValue},
+		"x-api-key":     []interface{}{"<X-Api-Key>"},
+	}, logs.All()[0].ContextMap()["headers"])
+}
+
+func TestZapLoggerValuesDisabled(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), Values: &RequestLoggerValues{LogStatus: true}})(h)(c)
+
+	assert.Nil(t, err)
+
+	logFields := logs.All()[0].ContextMap()
+	assert.Equal(t, int64(http.StatusOK), logFields["status"])
+	for _, key := range []string{"latency", "remote_ip", "host", "request", "size", "user_agent", "method", "uri"} {
+		assert.NotContains(t, logFields, key)
+	}
+}
@@ -0,0 +1,76 @@
+package echozap
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// headersObject marshals request headers as a zap object, multiple values being joined with ", "
+type headersObject struct {
+	header http.Header
+	// anonymize anonymizes the client IP headers
+	anonymize bool
+}
+
+func (h headersObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	names := make([]string, 0, len(h.header))
+	for name := range h.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.Join(h.header[name], ", ")
+		switch {
+		case containsString(sensitiveHeaders, name):
+			value = redactedValue
+		case h.anonymize && containsString(clientIPHeaders, name):
+			value = anonymizeIPHeader(value)
+		}
+		enc.AddString(name, value)
+	}
+	return nil
+}
+
+// paramsObject marshals the path parameters of a request as a zap object
+type paramsObject struct {
+	names  []string
+	values []string
+}
+
+// newParamsObject returns the path parameters of the request. They are copied, as echo reuses its slices
+// for the next request served with the context while cores may encode the object later.
+func newParamsObject(c echo.Context) paramsObject {
+	names, values := c.ParamNames(), c.ParamValues()
+	p := paramsObject{names: make([]string, len(names)), values: make([]string, len(values))}
+	copy(p.names, names)
+	copy(p.values, values)
+	return p
+}
+
+func (p paramsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for i, name := range p.names {
+		if i < len(p.values) {
+			enc.AddString(name, p.values[i])
+		}
+	}
+	return nil
+}
+
+// verboseFields returns the request details logged when the debug query parameter is set: headers,
+// declared body size, path parameters and query, sensitive headers and parameters being redacted
+func verboseFields(options *Options, c echo.Context) []zapcore.Field {
+	req := c.Request()
+
+	return []zapcore.Field{
+		zap.Object("request_headers", headersObject{header: req.Header, anonymize: options.AnonymizeIP}),
+		zap.Int64("request_size", req.ContentLength),
+		zap.Object("params", newParamsObject(c)),
+		zap.String("query", redactQuery(req.URL.Query(), req.URL.RawQuery, options.SensitiveParams)),
+	}
+}
@@ -0,0 +1,44 @@
+package echozap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerDebugQueryParam(t *testing.T) {
+	obs, logs := observer.New(zap.DebugLevel)
+
+	e := echo.New()
+	e.Use(ZapLogger(&Options{Logger: zap.New(obs), DebugQueryParam: "__debug", SensitiveParams: []string{"token"}}))
+	e.POST("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	for _, target := range []string{"/users/42?__debug=1&token=secret", "/users/42?token=secret"} {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader("name=gopher"))
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	verbose := logs.All()[0].ContextMap()
+	assert.Equal(t, map[string]interface{}{
+		echo.HeaderAuthorization: redactedValue,
+		echo.HeaderContentType:   echo.MIMEApplicationForm,
+	}, verbose["request_headers"])
+	assert.Equal(t, int64(11), verbose["request_size"])
+	assert.Equal(t, map[string]interface{}{"id": "42"}, verbose["params"])
+	assert.Equal(t, "__debug=1&token=%5BREDACTED%5D", verbose["query"])
+
+	base := logs.All()[1].ContextMap()
+	for _, field := range []string{"request_headers", "request_size", "params", "query"} {
+		assert.NotContains(t, base, field)
+	}
+}
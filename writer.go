@@ -0,0 +1,81 @@
+package echozap
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// responseWriter wraps the response writer to observe how the body is written. It keeps the
+// http.Flusher and http.Hijacker behaviour of the wrapped writer.
+type responseWriter struct {
+	http.ResponseWriter
+	clock func() time.Time
+
+	// firstWrite and lastWrite are the times of the first and of the end of the last Write
+	firstWrite time.Time
+	lastWrite  time.Time
+	// flushes is the number of flushes of the wrapped writer
+	flushes int
+	// capture keeps a copy of the body when set
+	capture *limitedBuffer
+	// onHijack is called once the connection is hijacked, when set
+	onHijack func()
+	// conn counts the bytes transferred on the hijacked connection
+	conn *countingConn
+}
+
+// newResponseWriter wraps w
+func newResponseWriter(w http.ResponseWriter, clock func() time.Time) *responseWriter {
+	return &responseWriter{ResponseWriter: w, clock: clock}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.firstWrite.IsZero() {
+		w.firstWrite = w.clock()
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.lastWrite = w.clock()
+	if w.capture != nil && n > 0 {
+		w.capture.Write(b[:n])
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher, it is a no-op when the wrapped writer can't flush
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+		w.flushes++
+	}
+}
+
+// Hijack implements http.Hijacker
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("echozap: response writer does not implement http.Hijacker")
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+
+	w.conn = &countingConn{Conn: conn}
+	if w.onHijack != nil {
+		w.onHijack()
+	}
+	return w.conn, rw, nil
+}
+
+// hijacked reports whether the connection was hijacked
+func (w *responseWriter) hijacked() bool {
+	return w.conn != nil
+}
+
+// written reports whether any body was written
+func (w *responseWriter) written() bool {
+	return !w.firstWrite.IsZero()
+}
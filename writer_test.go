@@ -0,0 +1,88 @@
+package echozap
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLoggerWriteTiming(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Write([]byte("first"))
+		time.Sleep(10 * time.Millisecond)
+		c.Response().Write([]byte("last"))
+		return nil
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogWriteTiming: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "firstlast", rec.Body.String())
+
+	logFields := logs.All()[0].ContextMap()
+	assert.True(t, logFields["ttfb_ms"].(float64) >= 20)
+	assert.True(t, logFields["write_duration_ms"].(float64) >= 10)
+}
+
+func TestZapLoggerWriteTimingNoBody(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/something", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogWriteTiming: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.NotContains(t, logs.All()[0].ContextMap(), "ttfb_ms")
+	assert.NotContains(t, logs.All()[0].ContextMap(), "write_duration_ms")
+}
+
+func TestZapLoggerFlushCount(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := func(c echo.Context) error {
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(res, "data: %d\n\n", i)
+			res.Flush()
+		}
+		return nil
+	}
+
+	obs, logs := observer.New(zap.DebugLevel)
+
+	err := ZapLogger(&Options{Logger: zap.New(obs), LogFlushCount: true})(h)(c)
+
+	assert.Nil(t, err)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "data: 0\n\ndata: 1\n\ndata: 2\n\n", rec.Body.String())
+	assert.Equal(t, int64(3), logs.All()[0].ContextMap()["flush_count"])
+	assert.NotContains(t, logs.All()[0].ContextMap(), "ttfb_ms")
+}